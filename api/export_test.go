@@ -0,0 +1,12 @@
+package api
+
+import (
+	"aws-ses-sender-go/pkg/sns"
+	"net/http"
+)
+
+// SetSNS replaces the client and verifier used for SNS messages
+func SetSNS(client *http.Client, verifier *sns.Verifier) {
+	snsClient = client
+	snsVerifier = func() *sns.Verifier { return verifier }
+}
@@ -1,11 +1,16 @@
 package api
 
 import (
+	"aws-ses-sender-go/cmd/collector"
 	"aws-ses-sender-go/cmd/sender"
 	"aws-ses-sender-go/config"
 	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/sns"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gofiber/fiber/v3"
 	"image"
 	"image/color"
@@ -15,35 +20,93 @@ import (
 	"time"
 )
 
+// submitTimeout bounds the validation (e.g. MX lookups) of a submitted message
+const submitTimeout = 10 * time.Second
+
+// messageResult Outcome of a single message in a send request
+type messageResult struct {
+	Index     int        `json:"index"`
+	Email     string     `json:"email"`
+	RequestId uint       `json:"requestId,omitempty"`
+	Status    string     `json:"status"` // accepted, suppressed or rejected
+	Reason    string     `json:"reason,omitempty"`
+	Duplicate bool       `json:"duplicate,omitempty"` // Already submitted with the same idempotency key
+	SendAt    *time.Time `json:"sendAt,omitempty"`    // Scheduled send time
+}
+
 // createMessageHandler Message Handler
 // Handler that receives email sending requests
 func createMessageHandler(c fiber.Ctx) error {
 	start := time.Now()
 	var reqBody struct {
-		Messages []struct {
-			TopicId string `json:"topicId"`
-			Email   string `json:"email"`
-			Subject string `json:"subject"`
-			Content string `json:"content"`
-		} `json:"messages"`
+		Messages []sender.Message `json:"messages"`
 	}
 	if err := c.Bind().JSON(&reqBody); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	for _, message := range reqBody.Messages {
-		// Request the sender to send the email
-		ctx := c.Context()
-		sender.Request(message.TopicId, message.Email, message.Subject, message.Content, ctx)
+	// The Idempotency-Key header covers the whole batch; each message gets a key derived from its position
+	batchKey := c.Get("Idempotency-Key")
+
+	results := make([]messageResult, 0, len(reqBody.Messages))
+	accepted, suppressed := 0, 0
+	for i, message := range reqBody.Messages {
+		if message.IdempotencyKey == "" && batchKey != "" {
+			message.IdempotencyKey = fmt.Sprintf("%s:%d", batchKey, i)
+		}
+		// Request the sender to send the email. Accepted messages belong to the service, not to this
+		// HTTP request, so the request context is not passed on and a disconnecting client cannot stop them.
+		ctx, cancel := context.WithTimeout(context.Background(), submitTimeout)
+		acc, err := sender.Request(message, ctx)
+		cancel()
+		if err != nil {
+			results = append(results, messageResult{Index: i, Email: message.Email, Status: "rejected", Reason: err.Error()})
+			continue
+		}
+		if acc.Suppressed != "" {
+			suppressed++
+			results = append(results, messageResult{Index: i, Email: message.Email, RequestId: acc.RequestId, Status: "suppressed", Reason: acc.Suppressed})
+			continue
+		}
+		accepted++
+		results = append(results, messageResult{
+			Index:     i,
+			Email:     message.Email,
+			RequestId: acc.RequestId,
+			Status:    "accepted",
+			Duplicate: acc.Duplicate,
+			SendAt:    acc.SendAt,
+		})
 	}
 
 	// Return the result
 	return c.JSON(fiber.Map{
-		"count":   len(reqBody.Messages),
-		"elapsed": time.Since(start).String(),
+		"count":      len(reqBody.Messages),
+		"accepted":   accepted,
+		"suppressed": suppressed,
+		"rejected":   len(reqBody.Messages) - accepted - suppressed,
+		"results":    results,
+		"elapsed":    time.Since(start).String(),
 	})
 }
 
+// cancelMessageHandler Stop a message that has not been sent yet
+func cancelMessageHandler(c fiber.Ctx) error {
+	requestId, err := strconv.Atoi(c.Params("requestId"))
+	if err != nil || requestId < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid requestId"})
+	}
+	switch err := sender.Cancel(uint(requestId)); {
+	case errors.Is(err, sender.ErrRequestNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, sender.ErrNotCancellable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"requestId": requestId, "status": "cancelled"})
+}
+
 // createOpenEventHandler Open Event Handler
 // Attach an image script to the email and assume it has been read when the image is accessed
 func createOpenEventHandler(c fiber.Ctx) error {
@@ -72,46 +135,36 @@ func createOpenEventHandler(c fiber.Ctx) error {
 // createResultEventHandler Result Event Handler
 // Handler that receives AWS SES results
 func createResultEventHandler(c fiber.Ctx) error {
-	var reqBody struct {
-		Type         string `json:"Type"`
-		Message      string `json:"Message"`
-		SubscribeURL string `json:"SubscribeURL"`
-	}
-	if err := c.Bind().JSON(&reqBody); err != nil {
+	// SNS posts JSON as text/plain, so decode the body directly
+	var reqBody sns.Message
+	if err := json.Unmarshal(c.Body(), &reqBody); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if reqBody.Type == "SubscriptionConfirmation" {
-		log.Println(reqBody.SubscribeURL)
-		return c.JSON(fiber.Map{})
-	} else if reqBody.Type != "Notification" {
-		return c.JSON(fiber.Map{})
-	}
-
-	// Retrieve message
-	var bodyMessage struct {
-		EventType string `json:"eventType"`
-		Mail      struct {
-			MessageId string `json:"messageId"`
-		} `json:"mail"`
-	}
-	if err := json.Unmarshal([]byte(reqBody.Message), &bodyMessage); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	if verifier := snsVerifier(); verifier != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := verifier.Verify(ctx, &reqBody); err != nil {
+			log.Printf("rejected SNS message %s from %s: %v", reqBody.MessageId, reqBody.TopicArn, err)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
 	}
-
-	// Retrieve message
-	db := config.GetDB()
-	var request model.Request
-	if err := db.Where("request_id = ?", bodyMessage.Mail.MessageId).First(&request).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	switch reqBody.Type {
+	case "SubscriptionConfirmation":
+		return confirmSubscription(c, &reqBody)
+	case "UnsubscribeConfirmation":
+		return recordUnsubscribe(c, &reqBody)
+	case "Notification":
+	default:
+		return c.JSON(fiber.Map{})
 	}
 
-	// Save result
-	result := model.Result{
-		RequestId: request.ID,
-		Status:    bodyMessage.EventType,
-		Raw:       reqBody.Message,
+	// Save result for the request the event belongs to
+	if _, err := collector.Record(reqBody.Message); err != nil {
+		if errors.Is(err, collector.ErrInvalidEvent) || errors.Is(err, collector.ErrUnknownMessage) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	_ = db.Create(&result).Error
 	return c.JSON(fiber.Map{})
 }
 
@@ -131,7 +184,7 @@ func getResultCountHandler(c fiber.Ctx) error {
 	}
 	if requestCount == 0 {
 		return c.JSON(fiber.Map{
-			"request": fiber.Map{"total": 0, "created": 0, "sent": 0, "failed": 0, "stopped": 0},
+			"request": fiber.Map{"total": 0, "created": 0, "sent": 0, "failed": 0, "stopped": 0, "scheduled": 0},
 			"result":  fiber.Map{"total": 0, "statuses": map[string]int{}},
 		})
 	}
@@ -150,11 +203,12 @@ func getResultCountHandler(c fiber.Ctx) error {
 	}
 
 	requestCounts := struct {
-		Total   int `json:"total"`
-		Created int `json:"created"`
-		Sent    int `json:"sent"`
-		Failed  int `json:"failed"`
-		Stopped int `json:"stopped"`
+		Total     int `json:"total"`
+		Created   int `json:"created"`
+		Sent      int `json:"sent"`
+		Failed    int `json:"failed"`
+		Stopped   int `json:"stopped"`
+		Scheduled int `json:"scheduled"`
 	}{Total: int(requestCount)} // Initialize Total with requestCount
 
 	for _, r := range requestResults {
@@ -167,6 +221,8 @@ func getResultCountHandler(c fiber.Ctx) error {
 			requestCounts.Failed = r.Count
 		case model.EmailMessageStatusStopped:
 			requestCounts.Stopped = r.Count
+		case model.EmailMessageStatusScheduled:
+			requestCounts.Scheduled = r.Count
 		}
 	}
 
@@ -227,3 +283,18 @@ func getSentCountHandler(c fiber.Ctx) error {
 	// Return the result
 	return c.JSON(fiber.Map{"count": count})
 }
+
+// getSenderStatusHandler Retrieve the dispatch state of the sender, including the daily quota pause
+func getSenderStatusHandler(c fiber.Ctx) error {
+	status := sender.GetStatus()
+	return c.JSON(fiber.Map{
+		"paused":          status.Paused,
+		"resumeAt":        status.ResumeAt,
+		"sentLast24Hours": status.SentLast24Hours,
+		"dailyQuota":      status.DailyQuota,
+		"pauseAt":         status.PauseAt,
+		"rate":            status.Rate,
+		"burst":           status.Burst,
+		"inFlight":        status.InFlight,
+	})
+}
@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"aws-ses-sender-go/api"
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/config/configtest"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	transport   = mail.NewMemoryTransport()
+	startSender sync.Once
+)
+
+func TestMain(m *testing.M) {
+	configtest.Main(m)
+}
+
+// runSender starts the send loop with the in-memory transport
+func runSender() {
+	startSender.Do(func() {
+		go sender.Consume(context.Background(), transport)
+		go sender.ConsumePostSend(context.Background())
+	})
+}
+
+// call sends a request to the app and decodes the JSON response
+func call(t *testing.T, app *fiber.App, method, path, body string) (int, map[string]any) {
+	return do(t, app, httptest.NewRequest(method, path, strings.NewReader(body)))
+}
+
+// do sends a prepared JSON request to the app and decodes the JSON response
+func do(t *testing.T, app *fiber.App, req *http.Request) (int, map[string]any) {
+	req.Header.Set("Content-Type", "application/json")
+	res, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	out := map[string]any{}
+	if len(data) > 0 {
+		require.NoError(t, json.Unmarshal(data, &out), string(data))
+	}
+	return res.StatusCode, out
+}
+
+// TestCreateMessage_CancelledContext tests that a message accepted by the handler is sent even though
+// the context of the HTTP request is cancelled, as when the client disconnects
+func TestCreateMessage_CancelledContext(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		c.SetContext(ctx)
+		return c.Next()
+	})
+	app.Use(api.New())
+
+	status, body := call(t, app, http.MethodPost, "/v1/messages",
+		`{"messages": [{"topicId": "handler", "email": "disconnected@example.com", "subject": "Subject", "content": "<p>Body</p>"}]}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 1, body["accepted"])
+	runSender()
+
+	assert.Eventually(t, func() bool {
+		return slices.ContainsFunc(transport.Messages(), func(m mail.Message) bool {
+			return slices.Contains(m.To, "disconnected@example.com")
+		})
+	}, 5*time.Second, 50*time.Millisecond)
+	var stored model.Request
+	require.NoError(t, config.GetDB().Where("`to` = ?", "disconnected@example.com").First(&stored).Error)
+	assert.NotEqual(t, model.EmailMessageStatusStopped, stored.Status)
+}
+
+// TestCreateMessage_IdempotencyKeyHeader tests that the Idempotency-Key header gives each message of the batch
+// its own key, so a retried batch returns the original requests
+func TestCreateMessage_IdempotencyKeyHeader(t *testing.T) {
+	app := api.New()
+	batch := "batch-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	body := `{"messages": [
+		{"topicId": "idempotency", "email": "first@example.com", "subject": "Subject", "content": "<p>Body</p>", "sendAt": "2999-01-01T00:00:00Z"},
+		{"topicId": "idempotency", "email": "second@example.com", "subject": "Subject", "content": "<p>Body</p>", "sendAt": "2999-01-01T00:00:00Z",
+		 "idempotencyKey": "` + batch + `-own"}]}`
+	send := func() []any {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", batch)
+		status, res := do(t, app, req)
+		require.Equal(t, http.StatusOK, status)
+		require.EqualValues(t, 2, res["accepted"])
+		return res["results"].([]any)
+	}
+
+	first := send()
+	for _, r := range first {
+		assert.Nil(t, r.(map[string]any)["duplicate"])
+	}
+	var keys []string
+	require.NoError(t, config.GetDB().Model(&model.Request{}).Where("topic_id = ? AND idempotency_key LIKE ?", "idempotency", batch+"%").
+		Order("id").Pluck("idempotency_key", &keys).Error)
+	assert.Equal(t, []string{batch + ":0", batch + "-own"}, keys) // A message's own key wins over the header
+
+	retried := send()
+	for i, r := range retried {
+		assert.Equal(t, true, r.(map[string]any)["duplicate"])
+		assert.Equal(t, first[i].(map[string]any)["requestId"], r.(map[string]any)["requestId"])
+	}
+}
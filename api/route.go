@@ -6,8 +6,32 @@ import "github.com/gofiber/fiber/v3"
 func setV1Routes(app *fiber.App) {
 	// Messages
 	app.Post("/v1/messages", createMessageHandler)
+	app.Post("/v1/messages/:requestId/cancel", cancelMessageHandler)
+	// Templates
+	app.Post("/v1/templates", createTemplateHandler)
+	app.Get("/v1/templates", getTemplatesHandler)
+	app.Get("/v1/templates/:templateId", getTemplateHandler)
+	app.Put("/v1/templates/:templateId", updateTemplateHandler)
+	app.Delete("/v1/templates/:templateId", deleteTemplateHandler)
+	app.Get("/v1/templates/:templateId/versions", getTemplateVersionsHandler)
+	app.Get("/v1/templates/:templateId/versions/:version", getTemplateVersionHandler)
+	app.Post("/v1/templates/:templateId/preview", previewTemplateHandler)
 	// Topics
+	app.Get("/v1/topics", getTopicsHandler)
 	app.Get("/v1/topics/:topicId", getResultCountHandler)
+	app.Get("/v1/topics/:topicId/settings", getTopicSettingsHandler)
+	app.Put("/v1/topics/:topicId/settings", updateTopicSettingsHandler)
+	app.Delete("/v1/topics/:topicId/settings", deleteTopicSettingsHandler)
+	app.Post("/v1/topics/:topicId/pause", pauseTopicHandler)
+	app.Post("/v1/topics/:topicId/resume", resumeTopicHandler)
+	app.Post("/v1/topics/:topicId/cancel", cancelTopicHandler)
+	// Suppressions
+	app.Get("/v1/suppressions", getSuppressionsHandler)
+	app.Post("/v1/suppressions", createSuppressionHandler)
+	app.Get("/v1/suppressions/:email", getSuppressionHandler)
+	app.Delete("/v1/suppressions/:email", deleteSuppressionHandler)
+	// Sender
+	app.Get("/v1/sender/status", getSenderStatusHandler)
 	// Events
 	app.Get("/v1/events/open", createOpenEventHandler)
 	app.Get("/v1/events/counts/sent", getSentCountHandler)
@@ -2,17 +2,23 @@ package api
 
 import (
 	"aws-ses-sender-go/config"
+	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v3/middleware/requestid"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/logger"
 )
 
-func Run() {
+// New builds the HTTP API
+func New() *fiber.App {
+	// Attachments are posted inline, so allow larger bodies than the default 4MB
+	bodyLimitMB, _ := strconv.Atoi(config.GetEnv("SERVER_BODY_LIMIT_MB", "25"))
 	app := fiber.New(
-		fiber.Config{AppName: "aws-ses-sender-go"},
+		fiber.Config{AppName: "aws-ses-sender-go", BodyLimit: bodyLimitMB * 1024 * 1024},
 	)
 
 	// Middleware
@@ -25,6 +31,29 @@ func Run() {
 
 	// Routes
 	setV1Routes(app)
+	return app
+}
 
-	log.Fatal(app.Listen(fmt.Sprintf(":%s", config.GetEnv("SERVER_PORT", "3000"))))
+// Run serves the HTTP API until ctx is done, then lets in-flight requests finish within shutdownTimeout
+func Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	app := New()
+	// Report the SNS settings at startup rather than on the first result event
+	snsVerifier()
+	stopped := make(chan struct{})
+	err := app.Listen(fmt.Sprintf(":%s", config.GetEnv("SERVER_PORT", "3000")), fiber.ListenConfig{
+		GracefulContext: ctx,
+		ShutdownTimeout: shutdownTimeout,
+		OnShutdownSuccess: func() {
+			close(stopped)
+		},
+		OnShutdownError: func(err error) {
+			log.Printf("HTTP server shutdown: %v", err)
+			close(stopped)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	<-stopped
+	return nil
 }
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/sns"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// snsClient fetches signing certificates and confirms subscriptions (replaceable in tests)
+var snsClient = &http.Client{Timeout: 10 * time.Second}
+
+// snsVerifier checks result events from SNS; nil when SNS_VERIFY_SIGNATURE=false (local testing only)
+var snsVerifier = sync.OnceValue(func() *sns.Verifier {
+	if config.GetEnv("SNS_VERIFY_SIGNATURE", "true") == "false" {
+		log.Printf("SNS signature verification is disabled")
+		return nil
+	}
+	topicArns := splitList(config.GetEnv("SNS_TOPIC_ARNS"))
+	if len(topicArns) == 0 {
+		log.Printf("SNS_TOPIC_ARNS is empty; every SNS message is rejected")
+	}
+	maxAge, err := time.ParseDuration(config.GetEnv("SNS_MAX_AGE", sns.DefaultMaxAge.String()))
+	if err != nil {
+		log.Printf("invalid SNS_MAX_AGE, using %s: %v", sns.DefaultMaxAge, err)
+		maxAge = sns.DefaultMaxAge
+	}
+	return &sns.Verifier{
+		Client:    snsClient,
+		CertHosts: splitList(config.GetEnv("SNS_CERT_HOSTS")),
+		TopicArns: topicArns,
+		MaxAge:    maxAge,
+	}
+})
+
+// splitList splits a comma-separated setting, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// confirmSubscription confirms a verified SubscriptionConfirmation (Verify only passes allow-listed topics)
+// and records the subscription
+func confirmSubscription(c fiber.Ctx, m *sns.Message) error {
+	verifier := snsVerifier()
+	if verifier == nil {
+		// Without signature verification anyone could post this; leave it to an operator
+		log.Printf("subscription to %s needs manual confirmation: %s", m.TopicArn, m.SubscribeURL)
+		return c.JSON(fiber.Map{})
+	}
+
+	db := config.GetDB()
+	subscription := model.Subscription{TopicArn: m.TopicArn}
+	if err := db.Where("topic_arn = ?", m.TopicArn).FirstOrInit(&subscription).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	arn, err := sns.Confirm(ctx, snsClient, verifier.CertHosts, m)
+	if err != nil {
+		log.Printf("failed to confirm subscription to %s: %v", m.TopicArn, err)
+		subscription.Status, subscription.Error = model.SubscriptionStatusFailed, err.Error()
+		_ = db.Save(&subscription).Error
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	now := time.Now()
+	subscription.SubscriptionArn = arn
+	subscription.Status, subscription.Error = model.SubscriptionStatusConfirmed, ""
+	subscription.ConfirmedAt, subscription.UnsubscribedAt = &now, nil
+	if err := db.Save(&subscription).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Printf("confirmed subscription %s", arn)
+	return c.JSON(fiber.Map{})
+}
+
+// recordUnsubscribe marks the topic's subscription as removed; it is not re-subscribed automatically
+func recordUnsubscribe(c fiber.Ctx, m *sns.Message) error {
+	now := time.Now()
+	res := config.GetDB().Model(&model.Subscription{}).
+		Where("topic_arn = ?", m.TopicArn).
+		Updates(map[string]any{"status": model.SubscriptionStatusUnsubscribed, "unsubscribed_at": &now})
+	if res.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": res.Error.Error()})
+	}
+	log.Printf("unsubscribed from %s", m.TopicArn)
+	return c.JSON(fiber.Map{})
+}
@@ -0,0 +1,211 @@
+package api_test
+
+import (
+	"aws-ses-sender-go/api"
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/sns"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const topicArn = "arn:aws:sns:ap-northeast-2:123456789012:ses-results"
+
+// snsServer serves a signing certificate and answers subscription confirmations like SNS
+type snsServer struct {
+	key       *rsa.PrivateKey
+	server    *httptest.Server
+	confirmed atomic.Int32
+}
+
+func newSNSServer(t *testing.T) *snsServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.ap-northeast-2.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	s := &snsServer{key: key}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("Action") == "ConfirmSubscription" {
+			s.confirmed.Add(1)
+			_, _ = w.Write([]byte(`<ConfirmSubscriptionResponse><ConfirmSubscriptionResult><SubscriptionArn>` +
+				r.URL.Query().Get("TopicArn") + `:2bcfbf39</SubscriptionArn></ConfirmSubscriptionResult></ConfirmSubscriptionResponse>`))
+			return
+		}
+		_, _ = w.Write(certPEM)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// use points the API at the server, trusting it for certificates and subscriptions of the allowed topics
+func (s *snsServer) use(topicArns ...string) {
+	api.SetSNS(s.server.Client(), &sns.Verifier{
+		Client:    s.server.Client(),
+		CertHosts: []string{"127.0.0.1"},
+		TopicArns: topicArns,
+	})
+}
+
+// message builds a signed subscription message of the given type for the topic
+func (s *snsServer) message(t *testing.T, messageType, topic string) string {
+	return s.sign(t, sns.Message{
+		Type:         messageType,
+		MessageId:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:        "2336412f37",
+		TopicArn:     topic,
+		Message:      "You have chosen to subscribe to the topic",
+		SubscribeURL: s.server.URL + "/?Action=ConfirmSubscription&TopicArn=" + topic + "&Token=2336412f37",
+		Timestamp:    time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+	})
+}
+
+// notification builds a signed notification of an SES event, published by the topic at the given time
+func (s *snsServer) notification(t *testing.T, topic, event string, at time.Time) string {
+	return s.sign(t, sns.Message{
+		Type:      "Notification",
+		MessageId: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:  topic,
+		Message:   event,
+		Timestamp: at.UTC().Format("2006-01-02T15:04:05.000Z"),
+	})
+}
+
+// sign signs the message the way SNS does and returns it as a request body
+func (s *snsServer) sign(t *testing.T, m sns.Message) string {
+	m.SignatureVersion = "2"
+	m.SigningCertURL = s.server.URL + "/SimpleNotificationService-test.pem"
+	canonical := "Message\n" + m.Message + "\nMessageId\n" + m.MessageId + "\n"
+	if m.Type == "Notification" {
+		canonical += "Timestamp\n" + m.Timestamp + "\nTopicArn\n" + m.TopicArn + "\nType\n" + m.Type + "\n"
+	} else {
+		canonical += "SubscribeURL\n" + m.SubscribeURL + "\nTimestamp\n" + m.Timestamp + "\nToken\n" + m.Token +
+			"\nTopicArn\n" + m.TopicArn + "\nType\n" + m.Type + "\n"
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	require.NoError(t, err)
+	m.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	body, err := json.Marshal(m)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// TestResultEvent_ConfirmsSubscription tests that a subscription of an allow-listed topic is confirmed and recorded
+func TestResultEvent_ConfirmsSubscription(t *testing.T) {
+	s := newSNSServer(t)
+	s.use(topicArn)
+
+	status, _ := call(t, api.New(), http.MethodPost, "/v1/events/result", s.message(t, "SubscriptionConfirmation", topicArn))
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int32(1), s.confirmed.Load())
+
+	var subscription model.Subscription
+	require.NoError(t, config.GetDB().Where("topic_arn = ?", topicArn).First(&subscription).Error)
+	assert.Equal(t, model.SubscriptionStatusConfirmed, subscription.Status)
+	assert.Equal(t, topicArn+":2bcfbf39", subscription.SubscriptionArn)
+	assert.NotNil(t, subscription.ConfirmedAt)
+
+	// UnsubscribeConfirmation marks it as removed
+	status, _ = call(t, api.New(), http.MethodPost, "/v1/events/result", s.message(t, "UnsubscribeConfirmation", topicArn))
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, config.GetDB().Where("topic_arn = ?", topicArn).First(&subscription).Error)
+	assert.Equal(t, model.SubscriptionStatusUnsubscribed, subscription.Status)
+	assert.NotNil(t, subscription.UnsubscribedAt)
+	assert.Equal(t, int32(1), s.confirmed.Load()) // Not re-subscribed
+}
+
+// TestResultEvent_NoAllowList tests that without a topic allow-list no message is accepted, since any AWS account
+// can sign messages of its own topic
+func TestResultEvent_NoAllowList(t *testing.T) {
+	s := newSNSServer(t)
+	s.use()
+	other := "arn:aws:sns:ap-northeast-2:999999999999:attacker"
+
+	status, _ := call(t, api.New(), http.MethodPost, "/v1/events/result", s.message(t, "SubscriptionConfirmation", other))
+
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Zero(t, s.confirmed.Load())
+	var count int64
+	config.GetDB().Model(&model.Subscription{}).Where("topic_arn = ?", other).Count(&count)
+	assert.Zero(t, count)
+}
+
+// TestResultEvent_UntrustedNotification tests that validly signed results from another topic or replayed
+// from long ago are rejected before they are recorded
+func TestResultEvent_UntrustedNotification(t *testing.T) {
+	s := newSNSServer(t)
+	s.use(topicArn)
+	db := config.GetDB()
+	request := model.Request{TopicId: "untrusted", MessageId: "0100018c-untrusted", To: "victim@example.com",
+		Subject: "Subject", Content: "Body", Status: model.EmailMessageStatusSent}
+	require.NoError(t, db.Create(&request).Error)
+	t.Cleanup(func() {
+		_ = sender.Unsuppress("victim@example.com")
+		db.Unscoped().Where("request_id = ?", request.ID).Delete(&model.Result{})
+		db.Unscoped().Delete(&request)
+	})
+	event := `{"eventType": "Complaint", "complaint": {"complaintFeedbackType": "abuse",
+		"complainedRecipients": [{"emailAddress": "victim@example.com"}]}, "mail": {"messageId": "0100018c-untrusted"}}`
+
+	for name, body := range map[string]string{
+		"other topic": s.notification(t, "arn:aws:sns:ap-northeast-2:999999999999:attacker", event, time.Now()),
+		"replayed":    s.notification(t, topicArn, event, time.Now().Add(-2*time.Hour)),
+	} {
+		status, _ := call(t, api.New(), http.MethodPost, "/v1/events/result", body)
+		assert.Equal(t, http.StatusForbidden, status, name)
+	}
+	var results, suppressions int64
+	db.Model(&model.Result{}).Where("request_id = ?", request.ID).Count(&results)
+	db.Model(&model.Suppression{}).Where("email = ?", "victim@example.com").Count(&suppressions)
+	assert.Zero(t, results)
+	assert.Zero(t, suppressions)
+
+	// The same event from the allowed topic, published now, is recorded
+	status, _ := call(t, api.New(), http.MethodPost, "/v1/events/result", s.notification(t, topicArn, event, time.Now()))
+	require.Equal(t, http.StatusOK, status)
+	db.Model(&model.Result{}).Where("request_id = ?", request.ID).Count(&results)
+	assert.EqualValues(t, 1, results)
+}
+
+// TestResultEvent_Forged tests that unsigned messages are rejected
+func TestResultEvent_Forged(t *testing.T) {
+	s := newSNSServer(t)
+	s.use(topicArn)
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s.message(t, "SubscriptionConfirmation", topicArn)), &m))
+	m["Signature"] = base64.StdEncoding.EncodeToString([]byte("forged"))
+	body, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	status, _ := call(t, api.New(), http.MethodPost, "/v1/events/result", string(body))
+
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Zero(t, s.confirmed.Load())
+}
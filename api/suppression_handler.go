@@ -0,0 +1,108 @@
+package api
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/address"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// suppressionReasons are the reasons accepted by the suppression endpoints
+var suppressionReasons = []string{model.SuppressionReasonBounce, model.SuppressionReasonComplaint, model.SuppressionReasonManual}
+
+// getSuppressionsHandler Retrieve the suppression list, newest first
+func getSuppressionsHandler(c fiber.Ctx) error {
+	// A negative limit would disable it, so both are bounded
+	limit, err := strconv.Atoi(c.Query("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be between 1 and 1000"})
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offset must not be negative"})
+	}
+
+	query := config.GetDB().Model(&model.Suppression{})
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	var suppressions []model.Suppression
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&suppressions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"total": total, "suppressions": suppressions})
+}
+
+// emailParam returns the :email parameter as a suppression key; the path may percent-encode it
+func emailParam(c fiber.Ctx) string {
+	email := c.Params("email")
+	if unescaped, err := url.PathUnescape(email); err == nil {
+		email = unescaped
+	}
+	return address.Key(email)
+}
+
+// getSuppressionHandler Retrieve the suppression entry of an address
+func getSuppressionHandler(c fiber.Ctx) error {
+	var suppression model.Suppression
+	err := config.GetDB().Where("email = ?", emailParam(c)).First(&suppression).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": sender.ErrSuppressionNotFound.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(suppression)
+}
+
+// createSuppressionHandler Add an address to the suppression list
+func createSuppressionHandler(c fiber.Ctx) error {
+	var reqBody struct {
+		Email  string `json:"email"`
+		Reason string `json:"reason"` // Defaults to manual
+		Detail string `json:"detail"`
+	}
+	if err := c.Bind().JSON(&reqBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	addr, err := address.Normalize(reqBody.Email)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if reqBody.Reason == "" {
+		reqBody.Reason = model.SuppressionReasonManual
+	}
+	if !slices.Contains(suppressionReasons, reqBody.Reason) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "reason must be one of " + strings.Join(suppressionReasons, ", ")})
+	}
+
+	suppression, err := sender.Suppress(addr.Address, reqBody.Reason, reqBody.Detail, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(suppression)
+}
+
+// deleteSuppressionHandler Remove an address from the suppression list
+func deleteSuppressionHandler(c fiber.Ctx) error {
+	err := sender.Unsuppress(emailParam(c))
+	if errors.Is(err, sender.ErrSuppressionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{})
+}
@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"aws-ses-sender-go/api"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSuppressions tests adding, listing, fetching and removing suppression entries
+func TestSuppressions(t *testing.T) {
+	app := api.New()
+
+	status, body := call(t, app, http.MethodPost, "/v1/suppressions", `{"email": "Listed@Example.com", "detail": "requested by user"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "listed@example.com", body["email"])
+	assert.Equal(t, "manual", body["reason"])
+	assert.NotEmpty(t, body["CreatedAt"])
+
+	status, _ = call(t, app, http.MethodPost, "/v1/suppressions", `{"email": "bounced@example.com", "reason": "bounce"}`)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = call(t, app, http.MethodPost, "/v1/suppressions", `{"email": "other@example.com", "reason": "unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(t, app, http.MethodPost, "/v1/suppressions", `{"email": "not an address"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body = call(t, app, http.MethodGet, "/v1/suppressions?reason=manual", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 1, body["total"])
+	require.Len(t, body["suppressions"], 1)
+	assert.Equal(t, "listed@example.com", body["suppressions"].([]any)[0].(map[string]any)["email"])
+
+	status, body = call(t, app, http.MethodGet, "/v1/suppressions?limit=1", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 2, body["total"])
+	assert.Len(t, body["suppressions"], 1)
+
+	status, body = call(t, app, http.MethodGet, "/v1/suppressions/LISTED@example.com", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "requested by user", body["detail"])
+
+	status, _ = call(t, app, http.MethodDelete, "/v1/suppressions/listed@example.com", "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = call(t, app, http.MethodDelete, "/v1/suppressions/listed@example.com", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = call(t, app, http.MethodGet, "/v1/suppressions/listed@example.com", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = call(t, app, http.MethodDelete, "/v1/suppressions/bounced@example.com", "")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+// TestSuppressions_Paging tests that the list rejects limits and offsets out of range
+func TestSuppressions_Paging(t *testing.T) {
+	app := api.New()
+	for _, query := range []string{"limit=-1", "limit=0", "limit=1001", "limit=abc", "offset=-1"} {
+		status, _ := call(t, app, http.MethodGet, "/v1/suppressions?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+	status, _ := call(t, app, http.MethodGet, "/v1/suppressions?limit=1000&offset=0", "")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+// TestSuppressions_IDN tests that fetching and removing an entry normalize the address like adding it
+func TestSuppressions_IDN(t *testing.T) {
+	app := api.New()
+
+	status, body := call(t, app, http.MethodPost, "/v1/suppressions", `{"email": "Reader@Bücher.Example"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "reader@xn--bcher-kva.example", body["email"])
+
+	path := "/v1/suppressions/" + url.PathEscape("reader@BÜCHER.example")
+	status, body = call(t, app, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "reader@xn--bcher-kva.example", body["email"])
+	status, _ = call(t, app, http.MethodDelete, path, "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = call(t, app, http.MethodGet, "/v1/suppressions/reader@xn--bcher-kva.example", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/render"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+type templateBody struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Html    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// validate checks required fields and that every part parses as a template
+func (b *templateBody) validate() error {
+	if b.Name == "" || b.Subject == "" || b.Html == "" {
+		return errors.New("name, subject and html are required")
+	}
+	_, err := render.Parse(b.Subject, b.Html, b.Text)
+	return err
+}
+
+// findTemplate loads the template referenced by the :templateId parameter
+func findTemplate(c fiber.Ctx) (*model.Template, error) {
+	id, err := strconv.Atoi(c.Params("templateId"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid templateId")
+	}
+	var tmpl model.Template
+	if err := config.GetDB().First(&tmpl, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "template not found")
+		}
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// errorResponse Return the error using the status of a fiber error (500 otherwise)
+func errorResponse(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+	}
+	return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+// saveTemplateVersion stores the body as the template's next immutable version
+func saveTemplateVersion(tmpl *model.Template, body *templateBody) error {
+	return config.GetDB().Transaction(func(tx *gorm.DB) error {
+		tmpl.Version++
+		tmpl.Subject = body.Subject
+		tmpl.Html = body.Html
+		tmpl.Text = body.Text
+		if err := tx.Save(tmpl).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.TemplateVersion{
+			TemplateId: tmpl.ID,
+			Version:    tmpl.Version,
+			Subject:    body.Subject,
+			Html:       body.Html,
+			Text:       body.Text,
+		}).Error
+	})
+}
+
+// createTemplateHandler Create a stored template
+func createTemplateHandler(c fiber.Ctx) error {
+	var reqBody templateBody
+	if err := c.Bind().JSON(&reqBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := reqBody.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tmpl := model.Template{Name: reqBody.Name}
+	if err := saveTemplateVersion(&tmpl, &reqBody); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(tmpl)
+}
+
+// getTemplatesHandler List stored templates
+func getTemplatesHandler(c fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be between 1 and 1000"})
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offset must not be negative"})
+	}
+
+	var templates []model.Template
+	if err := config.GetDB().Order("id").Limit(limit).Offset(offset).Find(&templates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"templates": templates})
+}
+
+// getTemplateHandler Retrieve a stored template
+func getTemplateHandler(c fiber.Ctx) error {
+	tmpl, err := findTemplate(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.JSON(tmpl)
+}
+
+// updateTemplateHandler Replace the contents of a stored template, creating a new version
+func updateTemplateHandler(c fiber.Ctx) error {
+	tmpl, err := findTemplate(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	var reqBody templateBody
+	if err := c.Bind().JSON(&reqBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := reqBody.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tmpl.Name = reqBody.Name
+	if err := saveTemplateVersion(tmpl, &reqBody); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(tmpl)
+}
+
+// deleteTemplateHandler Delete a stored template (requests already queued still render it)
+func deleteTemplateHandler(c fiber.Ctx) error {
+	tmpl, err := findTemplate(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	if err := config.GetDB().Delete(tmpl).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getTemplateVersionsHandler List the versions of a stored template
+func getTemplateVersionsHandler(c fiber.Ctx) error {
+	tmpl, err := findTemplate(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	var versions []model.TemplateVersion
+	if err := config.GetDB().
+		Where("template_id = ?", tmpl.ID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"versions": versions})
+}
+
+// getTemplateVersionHandler Retrieve a single version of a stored template
+func getTemplateVersionHandler(c fiber.Ctx) error {
+	tmpl, err := findTemplate(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	var tv model.TemplateVersion
+	if err := config.GetDB().
+		Where("template_id = ? AND version = ?", tmpl.ID, c.Params("version")).
+		First(&tv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "template version not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(tv)
+}
+
+// previewTemplateHandler Render a template with sample data and report missing variables
+func previewTemplateHandler(c fiber.Ctx) error {
+	tmpl, err := findTemplate(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	var reqBody struct {
+		Version int            `json:"version"` // Defaults to the latest version
+		Data    map[string]any `json:"data"`
+	}
+	if err := c.Bind().JSON(&reqBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	version := tmpl.Version
+	subject, html, text := tmpl.Subject, tmpl.Html, tmpl.Text
+	if reqBody.Version != 0 && reqBody.Version != tmpl.Version {
+		version = reqBody.Version
+		var tv model.TemplateVersion
+		if err := config.GetDB().
+			Where("template_id = ? AND version = ?", tmpl.ID, version).
+			First(&tv).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "template version not found"})
+		}
+		subject, html, text = tv.Subject, tv.Html, tv.Text
+	}
+
+	parsed, err := render.Parse(subject, html, text)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Render missing variables as empty values so the rest of the preview is still visible
+	missing := parsed.Missing(reqBody.Data)
+	data := make(map[string]any, len(reqBody.Data)+len(missing))
+	for k, v := range reqBody.Data {
+		data[k] = v
+	}
+	for _, k := range missing {
+		data[k] = ""
+	}
+	rendered, err := parsed.Execute(data)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error(), "missing": missing})
+	}
+	return c.JSON(fiber.Map{
+		"version":   version,
+		"subject":   rendered.Subject,
+		"html":      rendered.Html,
+		"text":      rendered.Text,
+		"variables": parsed.Variables(),
+		"missing":   missing,
+	})
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"aws-ses-sender-go/api"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTemplate creates a template through the API and deletes it with its versions when the test ends
+func createTemplate(t *testing.T, app *fiber.App, body string) string {
+	status, res := call(t, app, http.MethodPost, "/v1/templates", body)
+	require.Equal(t, http.StatusCreated, status, res)
+	id := strconv.Itoa(int(res["ID"].(float64)))
+	t.Cleanup(func() {
+		config.GetDB().Unscoped().Where("template_id = ?", id).Delete(&model.TemplateVersion{})
+		config.GetDB().Unscoped().Delete(&model.Template{}, id)
+	})
+	return id
+}
+
+// TestTemplates tests creating, listing, fetching, updating and deleting stored templates
+func TestTemplates(t *testing.T) {
+	app := api.New()
+	for _, body := range []string{
+		`{"name": "invalid", "subject": "Hi"}`,
+		`{"name": "invalid", "subject": "Hi {{.name", "html": "<p>Hi</p>"}`,
+		`not json`,
+	} {
+		status, _ := call(t, app, http.MethodPost, "/v1/templates", body)
+		assert.Equal(t, http.StatusBadRequest, status, body)
+	}
+
+	id := createTemplate(t, app, `{"name": "crud", "subject": "Hi {{.name}}", "html": "<p>Hi {{.name}}</p>"}`)
+	status, res := call(t, app, http.MethodGet, "/v1/templates/"+id, "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "crud", res["name"])
+	assert.EqualValues(t, 1, res["version"])
+
+	status, res = call(t, app, http.MethodGet, "/v1/templates?limit=1000", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, slices.ContainsFunc(res["templates"].([]any), func(tmpl any) bool {
+		return tmpl.(map[string]any)["name"] == "crud"
+	}))
+
+	status, _ = call(t, app, http.MethodPut, "/v1/templates/"+id, `{"name": "crud", "subject": "Hi"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, res = call(t, app, http.MethodPut, "/v1/templates/"+id, `{"name": "renamed", "subject": "Hello {{.name}}", "html": "<p>Hello</p>"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "renamed", res["name"])
+	assert.EqualValues(t, 2, res["version"])
+
+	status, res = call(t, app, http.MethodGet, "/v1/templates/"+id+"/versions", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, res["versions"], 2)
+	assert.EqualValues(t, 2, res["versions"].([]any)[0].(map[string]any)["version"]) // Newest first
+	status, res = call(t, app, http.MethodGet, "/v1/templates/"+id+"/versions/1", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Hi {{.name}}", res["subject"])
+	status, _ = call(t, app, http.MethodGet, "/v1/templates/"+id+"/versions/3", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = call(t, app, http.MethodDelete, "/v1/templates/"+id, "")
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = call(t, app, http.MethodGet, "/v1/templates/"+id, "")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = call(t, app, http.MethodDelete, "/v1/templates/"+id, "")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = call(t, app, http.MethodGet, "/v1/templates/abc", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+// TestTemplates_Paging tests that the list rejects limits and offsets out of range
+func TestTemplates_Paging(t *testing.T) {
+	app := api.New()
+	for _, query := range []string{"limit=-1", "limit=0", "limit=1001", "limit=abc", "offset=-1"} {
+		status, _ := call(t, app, http.MethodGet, "/v1/templates?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+	status, _ := call(t, app, http.MethodGet, "/v1/templates?limit=1000&offset=0", "")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+// TestPreviewTemplate tests that a preview renders the latest or a given version and reports missing variables
+func TestPreviewTemplate(t *testing.T) {
+	app := api.New()
+	id := createTemplate(t, app, `{"name": "preview", "subject": "Hi {{.name}}", "html": "<p>{{.name}} owes {{.amount}}</p>"}`)
+	status, res := call(t, app, http.MethodPut, "/v1/templates/"+id,
+		`{"name": "preview", "subject": "Hello {{.name}}", "html": "<p>{{.name}} owes {{.amount}} by {{.due}}</p>"}`)
+	require.Equal(t, http.StatusOK, status, res)
+
+	status, res = call(t, app, http.MethodPost, "/v1/templates/"+id+"/preview", `{"data": {"name": "Ada", "amount": 5}}`)
+	require.Equal(t, http.StatusOK, status, res)
+	assert.EqualValues(t, 2, res["version"])
+	assert.Equal(t, "Hello Ada", res["subject"])
+	assert.Equal(t, "<p>Ada owes 5 by </p>", res["html"])
+	assert.Equal(t, []any{"due"}, res["missing"])
+
+	status, res = call(t, app, http.MethodPost, "/v1/templates/"+id+"/preview", `{"version": 1, "data": {"name": "Ada", "amount": 5}}`)
+	require.Equal(t, http.StatusOK, status, res)
+	assert.EqualValues(t, 1, res["version"])
+	assert.Equal(t, "Hi Ada", res["subject"])
+	assert.Equal(t, "<p>Ada owes 5</p>", res["html"])
+	assert.Empty(t, res["missing"])
+
+	status, _ = call(t, app, http.MethodPost, "/v1/templates/"+id+"/preview", `{"version": 3}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = call(t, app, http.MethodPost, "/v1/templates/0/preview", `{}`)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// getTopicsHandler Retrieve the topics with delivery settings
+func getTopicsHandler(c fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be between 1 and 1000"})
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offset must not be negative"})
+	}
+
+	var topics []model.Topic
+	if err := config.GetDB().Order("topic_id").Limit(limit).Offset(offset).Find(&topics).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"topics": topics})
+}
+
+// getTopicSettingsHandler Retrieve the delivery settings of a topic
+func getTopicSettingsHandler(c fiber.Ctx) error {
+	topic := model.Topic{TopicId: c.Params("topicId")}
+	err := config.GetDB().Where("topic_id = ?", topic.TopicId).First(&topic).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(topic)
+}
+
+// updateTopicSettingsHandler Create or update the delivery settings of a topic
+func updateTopicSettingsHandler(c fiber.Ctx) error {
+	var reqBody struct {
+		MaxAttempts    int     `json:"maxAttempts"`    // 0 uses EMAIL_MAX_ATTEMPTS
+		MaxRate        float64 `json:"maxRate"`        // Sends per second, 0 is unlimited
+		MaxConcurrency int     `json:"maxConcurrency"` // Sends in flight, 0 is unlimited
+	}
+	if err := c.Bind().JSON(&reqBody); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if reqBody.MaxAttempts < 0 || reqBody.MaxRate < 0 || reqBody.MaxConcurrency < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "maxAttempts, maxRate and maxConcurrency must not be negative"})
+	}
+
+	db := config.GetDB()
+	topic := model.Topic{TopicId: c.Params("topicId")}
+	if err := db.Where("topic_id = ?", topic.TopicId).FirstOrInit(&topic).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	topic.MaxAttempts = reqBody.MaxAttempts
+	topic.MaxRate = reqBody.MaxRate
+	topic.MaxConcurrency = reqBody.MaxConcurrency
+	if err := db.Save(&topic).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(topic)
+}
+
+// deleteTopicSettingsHandler Reset a topic to the default delivery settings; a paused topic stays paused
+func deleteTopicSettingsHandler(c fiber.Ctx) error {
+	if err := config.GetDB().Model(&model.Topic{}).
+		Where("topic_id = ?", c.Params("topicId")).
+		Updates(map[string]any{"max_attempts": 0, "max_rate": 0, "max_concurrency": 0}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{})
+}
+
+// setTopicPaused Pause or resume dispatching a topic's unsent requests
+func setTopicPaused(c fiber.Ctx, paused bool) error {
+	db := config.GetDB()
+	topic := model.Topic{TopicId: c.Params("topicId")}
+	if err := db.Where("topic_id = ?", topic.TopicId).FirstOrInit(&topic).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	topic.Paused = paused
+	if err := db.Save(&topic).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	pending, err := sender.PendingCount(topic.TopicId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"topicId": topic.TopicId, "paused": paused, "pending": pending})
+}
+
+// pauseTopicHandler Stop dispatching a topic's unsent requests until it is resumed
+func pauseTopicHandler(c fiber.Ctx) error {
+	return setTopicPaused(c, true)
+}
+
+// resumeTopicHandler Dispatch a paused topic's unsent requests again
+func resumeTopicHandler(c fiber.Ctx) error {
+	return setTopicPaused(c, false)
+}
+
+// cancelTopicHandler Stop a topic's unsent requests for good
+func cancelTopicHandler(c fiber.Ctx) error {
+	cancelled, inFlight, err := sender.CancelTopic(c.Params("topicId"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"topicId": c.Params("topicId"), "cancelled": cancelled, "inFlight": inFlight})
+}
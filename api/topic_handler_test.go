@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"aws-ses-sender-go/api"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTopicPauseResumeCancel tests the pause, resume and cancel endpoints of a topic
+func TestTopicPauseResumeCancel(t *testing.T) {
+	app := api.New()
+	db := config.GetDB()
+	require.NoError(t, db.Unscoped().Where("topic_id = ?", "campaign").Delete(&model.Request{}).Error)
+
+	// Pause before queueing so a running sender cannot pick the requests up
+	status, body := call(t, app, http.MethodPost, "/v1/topics/campaign/pause", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"topicId": "campaign", "paused": true, "pending": float64(0)}, body)
+
+	for i := range 3 {
+		require.NoError(t, db.Create(&model.Request{
+			TopicId: "campaign",
+			To:      fmt.Sprintf("campaign-%d@example.com", i),
+			Subject: "Subject",
+			Content: "Body",
+		}).Error)
+	}
+	var topic model.Topic
+	require.NoError(t, db.Where("topic_id = ?", "campaign").First(&topic).Error)
+	assert.True(t, topic.Paused)
+
+	status, body = call(t, app, http.MethodPost, "/v1/topics/campaign/pause", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 3, body["pending"])
+
+	status, body = call(t, app, http.MethodPost, "/v1/topics/campaign/cancel", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"topicId": "campaign", "cancelled": float64(3), "inFlight": float64(0)}, body)
+
+	var stopped int64
+	require.NoError(t, db.Model(&model.Request{}).
+		Where("topic_id = ? AND status = ?", "campaign", model.EmailMessageStatusStopped).
+		Count(&stopped).Error)
+	assert.EqualValues(t, 3, stopped)
+
+	status, body = call(t, app, http.MethodPost, "/v1/topics/campaign/resume", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"topicId": "campaign", "paused": false, "pending": float64(0)}, body)
+	require.NoError(t, db.Where("topic_id = ?", "campaign").First(&topic).Error)
+	assert.False(t, topic.Paused)
+}
+
+// TestTopicSettingsReset tests that resetting a topic's delivery settings keeps a paused topic paused
+func TestTopicSettingsReset(t *testing.T) {
+	app := api.New()
+
+	status, _ := call(t, app, http.MethodPost, "/v1/topics/reset/pause", "")
+	require.Equal(t, http.StatusOK, status)
+	status, body := call(t, app, http.MethodPut, "/v1/topics/reset/settings", `{"maxAttempts": 3, "maxRate": 5, "maxConcurrency": 2}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, body["paused"])
+
+	status, _ = call(t, app, http.MethodDelete, "/v1/topics/reset/settings", "")
+	require.Equal(t, http.StatusOK, status)
+
+	status, body = call(t, app, http.MethodGet, "/v1/topics/reset/settings", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, body["paused"])
+	assert.EqualValues(t, 0, body["max_attempts"])
+	assert.EqualValues(t, 0, body["max_rate"])
+	assert.EqualValues(t, 0, body["max_concurrency"])
+
+	status, _ = call(t, app, http.MethodPost, "/v1/topics/reset/resume", "")
+	require.Equal(t, http.StatusOK, status)
+}
+
+// TestTopics_Paging tests that the list rejects limits and offsets out of range
+func TestTopics_Paging(t *testing.T) {
+	app := api.New()
+	for _, query := range []string{"limit=-1", "limit=0", "limit=1001", "limit=abc", "offset=-1"} {
+		status, _ := call(t, app, http.MethodGet, "/v1/topics?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+	status, _ := call(t, app, http.MethodGet, "/v1/topics?limit=1000&offset=0", "")
+	assert.Equal(t, http.StatusOK, status)
+}
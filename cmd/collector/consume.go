@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/pkg/aws"
+	"aws-ses-sender-go/pkg/clock"
+	"aws-ses-sender-go/pkg/sns"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Run records SES events from the SQS queue named by AWS_SQS_EVENTS_QUEUE_NAME until ctx is done.
+// It is disabled when the variable is empty; results then only arrive through POST /v1/events/result.
+func Run(ctx context.Context) {
+	name := config.GetEnv("AWS_SQS_EVENTS_QUEUE_NAME")
+	if name == "" {
+		return
+	}
+	sqsClient, err := aws.NewSQSClient(ctx)
+	if err != nil {
+		log.Printf("Failed to create SQS client, event collector disabled: %v", err)
+		return
+	}
+	// The queue is subscribed to the SES event topic beforehand, so it is never created here
+	queueUrl, err := sqsClient.GetQueueUrl(ctx, name)
+	if err != nil {
+		log.Printf("Failed to find event queue %s, event collector disabled: %v", name, err)
+		return
+	}
+	log.Printf("Event queue URL: %s", *queueUrl)
+
+	for ctx.Err() == nil {
+		messages, err := sqsClient.ReceiveMessages(ctx, queueUrl, 10, 10)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to receive events: %v", err)
+				clock.Sleep(ctx, 3*time.Second)
+			}
+			continue
+		}
+
+		for _, m := range messages {
+			if m.Body == nil {
+				log.Printf("Event body is nil")
+			} else if err := Handle(*m.Body); err != nil {
+				receives, _ := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+				if retain(err, receives) {
+					// Left on the queue; it is received again after the visibility timeout
+					log.Printf("Failed to record event %s (receive %d), retrying later: %v", *m.MessageId, receives, err)
+					continue
+				}
+				log.Printf("Failed to record event %s, dropping it: %v", *m.MessageId, err)
+			}
+			if err := sqsClient.DeleteMessage(context.WithoutCancel(ctx), queueUrl, m.ReceiptHandle); err != nil {
+				log.Printf("Failed to delete event: %v", err)
+			}
+		}
+	}
+	log.Printf("event collector stopped")
+}
+
+// maxReceives is how many times an event whose request is not known yet is received before it is dropped
+// (AWS_SQS_EVENTS_MAX_RECEIVES, default 5)
+func maxReceives() int {
+	n, err := strconv.Atoi(config.GetEnv("AWS_SQS_EVENTS_MAX_RECEIVES", "5"))
+	if err != nil || n < 1 {
+		return 5
+	}
+	return n
+}
+
+// retain reports whether an event that failed with err stays on the queue to be received again.
+// Events may arrive before the sender has stored the message ID of their request, so unmatched events
+// are retried up to maxReceives times; events that cannot be parsed never will be.
+func retain(err error, receives int) bool {
+	switch {
+	case err == nil, errors.Is(err, ErrInvalidEvent):
+		return false
+	case errors.Is(err, ErrUnknownMessage):
+		return receives < maxReceives()
+	default:
+		return true
+	}
+}
+
+// Handle records an SQS message body, which is either an SES event published through SNS
+// (wrapped in the SNS envelope) or the bare event when the subscription uses raw message delivery
+func Handle(body string) error {
+	var envelope sns.Message
+	if err := json.Unmarshal([]byte(body), &envelope); err == nil && envelope.Type != "" {
+		if envelope.Type != "Notification" {
+			// Subscriptions to queues in the same account are confirmed by SNS itself
+			log.Printf("ignoring SNS %s for %s", envelope.Type, envelope.TopicArn)
+			return nil
+		}
+		body = envelope.Message
+	}
+	_, err := Record(body)
+	return err
+}
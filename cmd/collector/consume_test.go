@@ -0,0 +1,82 @@
+package collector_test
+
+import (
+	"aws-ses-sender-go/cmd/collector"
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetain tests which failed events stay on the queue to be received again
+func TestRetain(t *testing.T) {
+	t.Setenv("AWS_SQS_EVENTS_MAX_RECEIVES", "3")
+
+	assert.False(t, collector.Retain(nil, 1))
+	assert.False(t, collector.Retain(collector.ErrInvalidEvent, 1))
+	assert.True(t, collector.Retain(collector.ErrUnknownMessage, 1))
+	assert.True(t, collector.Retain(collector.ErrUnknownMessage, 2))
+	assert.False(t, collector.Retain(collector.ErrUnknownMessage, 3), "an unmatched event is retried forever")
+	assert.True(t, collector.Retain(errors.New("database is locked"), 10))
+}
+
+// TestHandle_BeforeResultFlushed tests that an event arriving before the sender has stored the message ID
+// is kept for a later receive, and recorded once the result is flushed
+func TestHandle_BeforeResultFlushed(t *testing.T) {
+	t.Setenv("EMAIL_RESULT_FLUSH_INTERVAL", "1h")
+	transport := mail.NewMemoryTransport()
+	acc, err := sender.Request(sender.Message{
+		TopicId: "early",
+		Email:   "early@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db := config.GetDB()
+		db.Unscoped().Where("request_id = ?", acc.RequestId).Delete(&model.Result{})
+		db.Unscoped().Delete(&model.Request{}, acc.RequestId)
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	postSendCtx, stopPostSend := context.WithCancel(context.Background())
+	sendDone, postSendDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		sender.Consume(ctx, transport)
+		close(sendDone)
+	}()
+	go func() {
+		sender.ConsumePostSend(postSendCtx)
+		close(postSendDone)
+	}()
+	sent := func() int {
+		return slices.IndexFunc(transport.Messages(), func(m mail.Message) bool { return slices.Contains(m.To, "early@example.com") })
+	}
+	require.Eventually(t, func() bool { return sent() >= 0 }, 5*time.Second, 20*time.Millisecond)
+
+	// The memory transport numbers its messages; SES reports the send before the result is flushed
+	event := fmt.Sprintf(`{"eventType": "Send", "mail": {"messageId": "memory-%d"}}`, sent()+1)
+	err = collector.Handle(event)
+	require.ErrorIs(t, err, collector.ErrUnknownMessage)
+	assert.True(t, collector.Retain(err, 1))
+
+	// Stopping the sender flushes the result, as the next flush interval would
+	stop()
+	<-sendDone
+	stopPostSend()
+	<-postSendDone
+
+	require.NoError(t, collector.Handle(event))
+	var result model.Result
+	require.NoError(t, config.GetDB().Where("request_id = ?", acc.RequestId).First(&result).Error)
+	assert.Equal(t, "Send", result.Status)
+}
@@ -0,0 +1,4 @@
+package collector
+
+// Internals exercised by the external tests
+var Retain = retain
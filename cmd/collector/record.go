@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/address"
+	"aws-ses-sender-go/pkg/aws"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+)
+
+var (
+	ErrInvalidEvent   = errors.New("invalid SES event")
+	ErrUnknownMessage = errors.New("no request for SES message")
+)
+
+// Record stores an SES event as a result of the request it was sent for
+func Record(raw string) (*model.Result, error) {
+	event, err := aws.ParseSESEvent([]byte(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	db := config.GetDB()
+	var request model.Request
+	if err := db.Select("id", "to", "cc", "bcc").Where("message_id = ?", event.Mail.MessageId).First(&request).Error; err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrUnknownMessage, event.Mail.MessageId, err)
+	}
+
+	result := newResult(event)
+	result.RequestId = request.ID
+	result.Raw = raw
+	if err := db.Create(result).Error; err != nil {
+		return nil, err
+	}
+	suppress(event, &request, result.ID)
+	return result, nil
+}
+
+// suppress adds the recipients of permanent bounces and complaints to the suppression list. Only addresses
+// the request was sent to are suppressed: the message ID is visible to every recipient, so an event
+// must not be able to suppress arbitrary addresses.
+func suppress(event *aws.SESEvent, request *model.Request, resultId uint) {
+	var reason string
+	detail := map[string]string{}
+	switch {
+	case event.Bounce != nil && event.Bounce.BounceType == aws.SESBouncePermanent:
+		reason = model.SuppressionReasonBounce
+		for _, r := range event.Bounce.BouncedRecipients {
+			detail[r.EmailAddress] = r.DiagnosticCode
+		}
+	case event.Complaint != nil:
+		reason = model.SuppressionReasonComplaint
+		for _, r := range event.Complaint.ComplainedRecipients {
+			detail[r.EmailAddress] = event.Complaint.ComplaintFeedbackType
+		}
+	default:
+		return
+	}
+
+	sentTo := map[string]bool{}
+	for _, a := range slices.Concat([]string{request.To}, request.Cc, request.Bcc) {
+		sentTo[address.Key(a)] = true
+	}
+	for email, d := range detail {
+		if !sentTo[address.Key(email)] {
+			log.Printf("Not suppressing %s: not a recipient of request %d", email, request.ID)
+			continue
+		}
+		if _, err := sender.Suppress(email, reason, d, &resultId); err != nil {
+			log.Printf("Failed to suppress %s: %v", email, err)
+		}
+	}
+}
+
+// newResult maps the event details onto the result columns
+func newResult(event *aws.SESEvent) *model.Result {
+	result := &model.Result{
+		Status:    event.Type(),
+		MessageId: event.Mail.MessageId,
+	}
+	if at := event.Timestamp(); !at.IsZero() {
+		at = at.UTC()
+		result.EventAt = &at
+	}
+
+	switch {
+	case event.Bounce != nil:
+		result.BounceType = event.Bounce.BounceType
+		result.BounceSubType = event.Bounce.BounceSubType
+		result.ReportingMta = event.Bounce.ReportingMTA
+		result.Recipients = recipients(event.Bounce.BouncedRecipients)
+	case event.Complaint != nil:
+		result.ComplaintFeedbackType = event.Complaint.ComplaintFeedbackType
+		result.ComplaintSubType = event.Complaint.ComplaintSubType
+		for _, r := range event.Complaint.ComplainedRecipients {
+			result.Recipients = append(result.Recipients, model.ResultRecipient{EmailAddress: r.EmailAddress})
+		}
+	case event.DeliveryDelay != nil:
+		result.DelayType = event.DeliveryDelay.DelayType
+		result.ReportingMta = event.DeliveryDelay.ReportingMTA
+		result.Recipients = recipients(event.DeliveryDelay.DelayedRecipients)
+	case event.Delivery != nil:
+		result.ProcessingTimeMillis = event.Delivery.ProcessingTimeMillis
+		result.SmtpResponse = event.Delivery.SmtpResponse
+		result.ReportingMta = event.Delivery.ReportingMTA
+	case event.Reject != nil:
+		result.Reason = event.Reject.Reason
+	case event.Failure != nil:
+		result.Reason = event.Failure.ErrorMessage
+		result.TemplateName = event.Failure.TemplateName
+	case event.Subscription != nil:
+		result.ContactList = event.Subscription.ContactList
+	}
+	return result
+}
+
+func recipients(failures []aws.SESRecipientFailure) []model.ResultRecipient {
+	out := make([]model.ResultRecipient, 0, len(failures))
+	for _, f := range failures {
+		out = append(out, model.ResultRecipient{
+			EmailAddress:   f.EmailAddress,
+			Action:         f.Action,
+			Status:         f.Status,
+			DiagnosticCode: f.DiagnosticCode,
+		})
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package collector_test
+
+import (
+	"aws-ses-sender-go/cmd/collector"
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/config/configtest"
+	"aws-ses-sender-go/model"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	configtest.Main(m)
+}
+
+// TestRecord tests that an event is stored for the request matching its SES message ID
+func TestRecord(t *testing.T) {
+	db := config.GetDB()
+	request := model.Request{TopicId: "record", MessageId: "0100018c-record", To: "recipient@example.com", Subject: "Subject", Content: "Body"}
+	require.NoError(t, db.Create(&request).Error)
+	t.Cleanup(func() {
+		db.Unscoped().Where("request_id = ?", request.ID).Delete(&model.Result{})
+		db.Unscoped().Delete(&request)
+	})
+
+	result, err := collector.Record(`{"eventType": "Bounce", "bounce": {
+		"bounceType": "Permanent", "bounceSubType": "General", "timestamp": "2024-01-02T09:00:01Z",
+		"bouncedRecipients": [{"emailAddress": "recipient@example.com", "status": "5.1.1", "diagnosticCode": "smtp; 550 5.1.1 user unknown"}]},
+		"mail": {"messageId": "0100018c-record"}}`)
+	require.NoError(t, err)
+
+	var stored model.Result
+	require.NoError(t, db.Preload("Recipients").First(&stored, result.ID).Error)
+	assert.Equal(t, request.ID, stored.RequestId)
+	assert.Equal(t, "Bounce", stored.Status)
+	assert.Equal(t, "Permanent", stored.BounceType)
+	require.Len(t, stored.Recipients, 1)
+	assert.Equal(t, "smtp; 550 5.1.1 user unknown", stored.Recipients[0].DiagnosticCode)
+
+	// A permanent bounce suppresses the recipient
+	t.Cleanup(func() { _ = sender.Unsuppress("recipient@example.com") })
+	var suppression model.Suppression
+	require.NoError(t, db.Where("email = ?", "recipient@example.com").First(&suppression).Error)
+	assert.Equal(t, model.SuppressionReasonBounce, suppression.Reason)
+	assert.Equal(t, "smtp; 550 5.1.1 user unknown", suppression.Detail)
+	assert.Equal(t, &stored.ID, suppression.ResultId)
+
+	_, err = collector.Record(`{"eventType": "Delivery", "mail": {"messageId": "unknown"}}`)
+	assert.ErrorIs(t, err, collector.ErrUnknownMessage)
+	_, err = collector.Record(`{}`)
+	assert.ErrorIs(t, err, collector.ErrInvalidEvent)
+}
+
+// TestHandle tests that SQS bodies are recorded with and without the SNS envelope
+func TestHandle(t *testing.T) {
+	db := config.GetDB()
+	request := model.Request{TopicId: "record", MessageId: "0100018c-handle", To: "recipient@example.com", Subject: "Subject", Content: "Body"}
+	require.NoError(t, db.Create(&request).Error)
+	t.Cleanup(func() {
+		db.Unscoped().Where("request_id = ?", request.ID).Delete(&model.Result{})
+		db.Unscoped().Delete(&request)
+	})
+
+	event := `{"eventType": "Delivery", "delivery": {"processingTimeMillis": 546}, "mail": {"messageId": "0100018c-handle"}}`
+	envelope, err := json.Marshal(map[string]string{
+		"Type":     "Notification",
+		"TopicArn": "arn:aws:sns:ap-northeast-2:123456789012:ses-results",
+		"Message":  event,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, collector.Handle(string(envelope)))
+	require.NoError(t, collector.Handle(event)) // Raw message delivery
+
+	var results []model.Result
+	require.NoError(t, db.Where("request_id = ?", request.ID).Find(&results).Error)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		assert.Equal(t, "Delivery", r.Status)
+		assert.Equal(t, int64(546), r.ProcessingTimeMillis)
+		assert.JSONEq(t, event, r.Raw)
+	}
+	assert.NoError(t, collector.Handle(`{"Type": "SubscriptionConfirmation", "Message": "You have chosen to subscribe"}`))
+}
+
+// TestRecord_SuppressesOnlyRecipients tests that an event cannot suppress addresses the request was not sent to
+func TestRecord_SuppressesOnlyRecipients(t *testing.T) {
+	db := config.GetDB()
+	request := model.Request{TopicId: "record", MessageId: "0100018c-complaint", To: "to@example.com",
+		Cc: []string{"Copy <Copy@Example.com>"}, Subject: "Subject", Content: "Body"}
+	require.NoError(t, db.Create(&request).Error)
+	t.Cleanup(func() {
+		for _, email := range []string{"to@example.com", "copy@example.com", "victim@example.com"} {
+			_ = sender.Unsuppress(email)
+		}
+		db.Unscoped().Where("request_id = ?", request.ID).Delete(&model.Result{})
+		db.Unscoped().Delete(&request)
+	})
+
+	_, err := collector.Record(`{"eventType": "Complaint", "complaint": {"complaintFeedbackType": "abuse",
+		"complainedRecipients": [{"emailAddress": "to@example.com"}, {"emailAddress": "copy@example.com"}, {"emailAddress": "victim@example.com"}]},
+		"mail": {"messageId": "0100018c-complaint", "destination": ["victim@example.com"]}}`)
+	require.NoError(t, err)
+
+	var suppressed []string
+	require.NoError(t, db.Model(&model.Suppression{}).Order("email").Pluck("email", &suppressed).Error)
+	assert.Equal(t, []string{"copy@example.com", "to@example.com"}, suppressed)
+}
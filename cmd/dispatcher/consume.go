@@ -3,14 +3,16 @@ package dispatcher
 import (
 	"aws-ses-sender-go/cmd/sender"
 	"aws-ses-sender-go/pkg/aws"
+	"aws-ses-sender-go/pkg/clock"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 )
 
-func Run() {
-	ctx := context.Background()
+// Run consumes send requests from SQS until ctx is done
+func Run(ctx context.Context) {
 	sqsClient, err := aws.NewSQSClient(ctx)
 	if err != nil {
 		log.Fatalf("Failed to create SQS client: %v", err)
@@ -18,16 +20,22 @@ func Run() {
 
 	queueUrl, err := sqsClient.GetOrCreateQueue(ctx)
 	if err != nil {
+		if ctx.Err() != nil {
+			// Shutting down before the queue was ready
+			return
+		}
 		log.Fatalf("Failed to create (or verify) the queue: %v", err)
 	}
 	log.Printf("Queue URL: %s", *queueUrl)
 
 	// Loop to consume (ReceiveMessage) messages
-	for {
+	for ctx.Err() == nil {
 		messages, err := sqsClient.ReceiveMessages(ctx, queueUrl, 1, 10)
 		if err != nil {
-			log.Printf("Failed to receive message: %v", err)
-			time.Sleep(3 * time.Second)
+			if ctx.Err() == nil {
+				log.Printf("Failed to receive message: %v", err)
+				clock.Sleep(ctx, 3*time.Second)
+			}
 			continue
 		}
 
@@ -35,12 +43,8 @@ func Run() {
 			// Process messages immediately if present
 			for _, m := range messages {
 				var reqBody struct {
-					Messages []struct {
-						TopicId string `json:"topicId"`
-						Email   string `json:"email"`
-						Subject string `json:"subject"`
-						Content string `json:"content"`
-					} `json:"messages"`
+					IdempotencyKey string           `json:"idempotencyKey"` // Batch key, like the HTTP Idempotency-Key header
+					Messages       []sender.Message `json:"messages"`
 				}
 
 				if m.Body != nil {
@@ -48,23 +52,29 @@ func Run() {
 						log.Printf("Failed to parse JSON message: %v", err)
 					} else {
 						// Request the sender to send the email
-						for _, message := range reqBody.Messages {
+						for i, message := range reqBody.Messages {
+							if message.IdempotencyKey == "" && reqBody.IdempotencyKey != "" {
+								message.IdempotencyKey = fmt.Sprintf("%s:%d", reqBody.IdempotencyKey, i)
+							}
 							ctx := context.Background()
-							sender.Request(message.TopicId, message.Email, message.Subject, message.Content, ctx)
+							if _, err := sender.Request(message, ctx); err != nil {
+								log.Printf("Rejected message for %s: %v", message.Email, err)
+							}
 						}
 					}
 				} else {
 					log.Printf("Message body is nil")
 				}
 
-				// Delete the message after processing
-				if err := sqsClient.DeleteMessage(ctx, queueUrl, m.ReceiptHandle); err != nil {
+				// Delete the message after processing; a received batch is finished even when shutting down
+				if err := sqsClient.DeleteMessage(context.WithoutCancel(ctx), queueUrl, m.ReceiptHandle); err != nil {
 					log.Printf("Failed to delete message: %v", err)
 				}
 			}
 		} else {
 			// Wait for 3 seconds if no messages are present
-			time.Sleep(3 * time.Second)
+			clock.Sleep(ctx, 3*time.Second)
 		}
 	}
+	log.Printf("dispatcher stopped")
 }
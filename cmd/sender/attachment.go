@@ -0,0 +1,95 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// blobPath resolves a path relative to BLOB_STORE_DIR, refusing paths that escape it
+func blobPath(path string) (string, error) {
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("invalid blob path: %s", path)
+	}
+	return filepath.Join(config.GetEnv("BLOB_STORE_DIR", "blobs"), path), nil
+}
+
+// newAttachments validates the requested attachments and converts them for storage
+func newAttachments(attachments []Attachment) ([]model.Attachment, error) {
+	result := make([]model.Attachment, 0, len(attachments))
+	for _, a := range attachments {
+		att := model.Attachment{
+			Filename:  a.Filename,
+			ContentId: a.ContentId,
+		}
+		// The content type, content ID and filename end up in MIME headers
+		if a.ContentType != "" {
+			mediaType, params, err := mime.ParseMediaType(a.ContentType)
+			if err != nil {
+				return nil, fmt.Errorf("invalid attachment content type %q: %w", a.ContentType, err)
+			}
+			att.ContentType = mime.FormatMediaType(mediaType, params)
+		}
+		if strings.ContainsFunc(a.ContentId, unicode.IsControl) {
+			return nil, fmt.Errorf("invalid attachment content ID %q", a.ContentId)
+		}
+		switch {
+		case a.Content != "":
+			data, err := base64.StdEncoding.DecodeString(a.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid attachment content for %s: %w", a.Filename, err)
+			}
+			att.Data = data
+		case a.Path != "":
+			path, err := blobPath(a.Path)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("attachment not found: %s", a.Path)
+			}
+			att.Path = a.Path
+			if att.Filename == "" {
+				att.Filename = filepath.Base(a.Path)
+			}
+		default:
+			return nil, fmt.Errorf("attachment %s has neither content nor path", a.Filename)
+		}
+		if strings.ContainsFunc(att.Filename, unicode.IsControl) {
+			return nil, fmt.Errorf("invalid attachment filename %q", att.Filename)
+		}
+		result = append(result, att)
+	}
+	return result, nil
+}
+
+// loadAttachments reads the stored attachments, fetching blob store contents from disk
+func loadAttachments(attachments []model.Attachment) ([]mail.Attachment, error) {
+	result := make([]mail.Attachment, 0, len(attachments))
+	for _, a := range attachments {
+		data := a.Data
+		if a.Path != "" {
+			path, err := blobPath(a.Path)
+			if err != nil {
+				return nil, err
+			}
+			if data, err = os.ReadFile(path); err != nil {
+				return nil, fmt.Errorf("failed to read attachment: %w", err)
+			}
+		}
+		result = append(result, mail.Attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			ContentId:   a.ContentId,
+			Data:        data,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequest_AttachmentHeaders tests that attachment fields which end up in MIME headers cannot inject headers
+func TestRequest_AttachmentHeaders(t *testing.T) {
+	for name, a := range map[string]sender.Attachment{
+		"content type":     {Filename: "a.txt", ContentType: "text/plain\r\nBcc: victim@example.com"},
+		"content type arg": {Filename: "a.txt", ContentType: "text/plain; charset=\"utf-8\r\nBcc: victim@example.com\""},
+		"content ID":       {Filename: "a.png", ContentId: "logo>\r\nBcc: victim@example.com"},
+		"filename":         {Filename: "a.txt\r\nBcc: victim@example.com"},
+		"tab filename":     {Filename: "a\t.txt"},
+	} {
+		a.Content = "Ym9keQ=="
+		_, err := sender.Request(sender.Message{
+			TopicId:     "attachments",
+			Email:       "attached@example.com",
+			Subject:     "Subject",
+			Content:     "<p>Body</p>",
+			Attachments: []sender.Attachment{a},
+		}, context.Background())
+		assert.ErrorContains(t, err, "invalid attachment", name)
+	}
+
+	acc, err := sender.Request(sender.Message{
+		TopicId: "attachments",
+		Email:   "attached@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+		SendAt:  "2999-01-01T00:00:00Z",
+		Attachments: []sender.Attachment{
+			{Filename: "a.txt", ContentType: "text/plain; charset=utf-8", Content: "Ym9keQ=="},
+			{Filename: "logo.png", ContentId: "logo@example.com", Content: "Ym9keQ=="},
+		},
+	}, context.Background())
+	require.NoError(t, err)
+	config.GetDB().Unscoped().Delete(&model.Request{}, acc.RequestId)
+}
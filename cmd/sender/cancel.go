@@ -0,0 +1,62 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRequestNotFound = errors.New("request not found")
+	ErrNotCancellable  = errors.New("request is already sent or being sent")
+)
+
+// pendingStatuses are the statuses of requests that have not been sent yet
+var pendingStatuses = []int{model.EmailMessageStatusCreated, model.EmailMessageStatusScheduled}
+
+// Cancel stops a request that has not been sent yet
+func Cancel(requestId uint) error {
+	db := config.GetDB()
+	res := db.Model(&model.Request{}).
+		Where("id = ? AND status IN ?", requestId, pendingStatuses).
+		Where("lease_until IS NULL OR lease_until < ?", time.Now().UTC()).
+		Updates(map[string]any{"status": model.EmailMessageStatusStopped, "error": "cancelled"})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&model.Request{}).Where("id = ?", requestId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrRequestNotFound
+	}
+	return ErrNotCancellable
+}
+
+// CancelTopic stops the topic's requests that are not sent yet and returns how many were stopped.
+// Requests already claimed by the sender cannot be called back; their count is returned as inFlight.
+func CancelTopic(topicId string) (cancelled, inFlight int64, err error) {
+	res := config.GetDB().Model(&model.Request{}).
+		Where("topic_id = ? AND status IN ?", topicId, pendingStatuses).
+		Where("lease_until IS NULL OR lease_until < ?", time.Now().UTC()).
+		Updates(map[string]any{"status": model.EmailMessageStatusStopped, "error": "cancelled"})
+	if res.Error != nil {
+		return 0, 0, res.Error
+	}
+	inFlight, err = PendingCount(topicId)
+	return res.RowsAffected, inFlight, err
+}
+
+// PendingCount returns the number of the topic's requests that are not sent yet
+func PendingCount(topicId string) (int64, error) {
+	var count int64
+	err := config.GetDB().Model(&model.Request{}).
+		Where("topic_id = ? AND status IN ?", topicId, pendingStatuses).
+		Count(&count).Error
+	return count, err
+}
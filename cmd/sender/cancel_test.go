@@ -0,0 +1,102 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// claimOne claims the only claimable request
+func claimOne(t *testing.T) sender.ClaimedRequest {
+	reqs, err := sender.Claim(10, nil)
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	return reqs[0]
+}
+
+// TestPauseResume tests that a paused topic's requests are not claimed until it is resumed
+func TestPauseResume(t *testing.T) {
+	resetQueue(t)
+	setTopic(t, model.Topic{TopicId: "paused", Paused: true})
+	paused := enqueue(t, "paused", model.EmailPriorityNormal, 2)
+	other := enqueue(t, "running", model.EmailPriorityNormal, 1)
+
+	assert.Equal(t, other, claimedIds(t, 10))
+	pending, err := sender.PendingCount("paused")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, pending)
+
+	require.NoError(t, config.GetDB().Model(&model.Topic{}).Where("topic_id = ?", "paused").Update("paused", false).Error)
+	assert.ElementsMatch(t, paused, claimedIds(t, 10))
+}
+
+// TestCancelTopic tests that cancelling a topic stops its unsent requests and reports the ones being sent
+func TestCancelTopic(t *testing.T) {
+	resetQueue(t)
+	ids := enqueue(t, "cancelled", model.EmailPriorityNormal, 3)
+	other := enqueue(t, "kept", model.EmailPriorityNormal, 1)
+	require.NoError(t, config.GetDB().Model(&model.Request{}).Where("id IN ?", other).Update("lease_until", time.Now().Add(time.Hour)).Error)
+	require.NoError(t, config.GetDB().Model(&model.Request{}).Where("id = ?", ids[0]).Update("lease_until", time.Now().Add(time.Hour)).Error)
+
+	cancelled, inFlight, err := sender.CancelTopic("cancelled")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, cancelled)
+	assert.EqualValues(t, 1, inFlight)
+
+	for _, id := range ids[1:] {
+		m := leased(t, id)
+		assert.Equal(t, model.EmailMessageStatusStopped, m.Status)
+	}
+	assert.Equal(t, model.EmailMessageStatusCreated, leased(t, ids[0]).Status)
+	assert.Equal(t, model.EmailMessageStatusCreated, leased(t, other[0]).Status)
+}
+
+// TestCancel_LateRetryResult tests that a retry result recorded after the request was cancelled does not re-queue it
+func TestCancel_LateRetryResult(t *testing.T) {
+	t.Setenv("EMAIL_LEASE_TIMEOUT", "100ms")
+	resetQueue(t)
+	ids := enqueue(t, "late", model.EmailPriorityNormal, 1)
+
+	r := claimOne(t)
+	transport.FailNext(1, errThrottled)
+	res := sender.Deliver(transport, &r)
+	require.Equal(t, model.EmailMessageStatusCreated, res.Status)
+
+	// The result is still buffered when the lease runs out and the request is cancelled
+	time.Sleep(150 * time.Millisecond)
+	require.NoError(t, sender.Cancel(ids[0]))
+	sender.FlushResults(res)
+
+	m := stored(t, ids[0], model.EmailMessageStatusStopped)
+	assert.Equal(t, "cancelled", m.Error)
+	assert.Zero(t, m.Attempts)
+	assert.Empty(t, claimedIds(t, 10))
+}
+
+// TestResult_ClaimedAgain tests that only the latest claim of a request records its result
+func TestResult_ClaimedAgain(t *testing.T) {
+	t.Setenv("EMAIL_LEASE_TIMEOUT", "100ms")
+	resetQueue(t)
+	ids := enqueue(t, "reclaimed", model.EmailPriorityNormal, 1)
+
+	first := claimOne(t)
+	time.Sleep(150 * time.Millisecond)
+	second := claimOne(t)
+
+	transport.FailNext(1, errThrottled)
+	sender.FlushResults(sender.Deliver(transport, &first))
+	m := leased(t, ids[0])
+	assert.Equal(t, model.EmailMessageStatusCreated, m.Status)
+	require.NotNil(t, m.ClaimToken)
+	assert.Equal(t, second.ClaimToken, *m.ClaimToken, "a stale claim recorded its result")
+
+	sender.FlushResults(sender.Deliver(transport, &second))
+	m = stored(t, ids[0], model.EmailMessageStatusSent)
+	assert.Equal(t, 1, m.Attempts)
+	assert.Nil(t, m.ClaimToken)
+}
@@ -0,0 +1,49 @@
+package sender
+
+import "time"
+
+// ClaimedRequest is a request returned by Claim
+type ClaimedRequest = request
+
+// Internals exercised by the external tests
+var (
+	LaneLimits    = laneLimits
+	Claim         = claim
+	Release       = release
+	RecoverLeases = recoverLeases
+	Deliver       = deliver
+	ParseSendAt   = parseSendAt
+	ReleaseDue    = releaseDue
+	BatchSize     = schedulerBatchSize
+
+	AcquireTopic    = acquireTopic
+	ReleaseTopic    = releaseTopic
+	SaturatedTopics = saturatedTopics
+)
+
+// HasBudget reports whether a send budget is tracked for the topic
+func HasBudget(topicId string) bool {
+	budgets.Lock()
+	defer budgets.Unlock()
+	_, ok := budgets.byId[topicId]
+	return ok
+}
+
+// ReloadTopicSettings makes the next lookup read the topic settings from the database
+func ReloadTopicSettings() {
+	topics.Lock()
+	topics.loaded = time.Time{}
+	topics.Unlock()
+}
+
+// ResetLaneCredit forgets the lane shares carried over from earlier claims
+func ResetLaneCredit() {
+	laneCredit.Lock()
+	laneCredit.credit = nil
+	laneCredit.Unlock()
+}
+
+// FlushResults records send results as ConsumePostSend does
+func FlushResults(results ...result) {
+	flushBuffer(&results)
+}
@@ -0,0 +1,84 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestRequest_Idempotent tests that a repeated idempotency key returns the original request and sends once
+func TestRequest_Idempotent(t *testing.T) {
+	key := "once-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	email := key + "@example.com"
+	message := sender.Message{TopicId: "idempotent", Email: email, Subject: "Subject", Content: "<p>Body</p>", IdempotencyKey: key}
+
+	first, err := sender.Request(message, context.Background())
+	require.NoError(t, err)
+	assert.False(t, first.Duplicate)
+	message.Subject = "Changed" // The original request stands
+	again, err := sender.Request(message, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, sender.Accepted{RequestId: first.RequestId, Duplicate: true}, again)
+
+	startSender(t)
+	stored(t, first.RequestId, model.EmailMessageStatusSent)
+	again, err = sender.Request(message, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, first.RequestId, again.RequestId)
+	time.Sleep(200 * time.Millisecond)
+
+	sent := slices.DeleteFunc(transport.Messages(), func(m mail.Message) bool { return !slices.Contains(m.To, email) })
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Subject", sent[0].Subject)
+	var count int64
+	require.NoError(t, config.GetDB().Model(&model.Request{}).Where("idempotency_key = ?", key).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
+
+// TestRequest_IdempotencyRace tests that a request losing the unique index to a concurrent one with the same
+// key returns the winner instead of an error
+func TestRequest_IdempotencyRace(t *testing.T) {
+	key := "race-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	db := config.GetDB()
+
+	// Store the concurrent submission right after Request has looked the key up and found nothing
+	var winner model.Request
+	var once sync.Once
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:idempotency_race", func(tx *gorm.DB) {
+		if tx.Statement.Table != "email_requests" || !slices.Contains(tx.Statement.Vars, any(key)) {
+			return
+		}
+		once.Do(func() {
+			winner = model.Request{TopicId: "race", To: "winner@example.com", Subject: "Subject", Content: "Body",
+				Status: model.EmailMessageStatusStopped, IdempotencyKey: &key}
+			require.NoError(t, db.Session(&gorm.Session{NewDB: true}).Create(&winner).Error)
+		})
+	}))
+	t.Cleanup(func() { _ = db.Callback().Query().Remove("test:idempotency_race") })
+
+	acc, err := sender.Request(sender.Message{
+		TopicId:        "race",
+		Email:          "loser@example.com",
+		Subject:        "Subject",
+		Content:        "<p>Body</p>",
+		IdempotencyKey: key,
+	}, context.Background())
+
+	require.NoError(t, err)
+	require.NotZero(t, winner.ID, "the race was not staged")
+	assert.Equal(t, sender.Accepted{RequestId: winner.ID, Duplicate: true}, acc)
+	var count int64
+	require.NoError(t, db.Model(&model.Request{}).Where("idempotency_key = ?", key).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
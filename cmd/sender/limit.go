@@ -0,0 +1,89 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/pkg/aws"
+	"aws-ses-sender-go/pkg/ratelimit"
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quotaProvider is implemented by transports that can report the account's sending quota
+type quotaProvider interface {
+	Quota(ctx context.Context) (*aws.Quota, error)
+}
+
+var (
+	// limiter paces transport calls across all topics
+	limiter = sync.OnceValue(newLimiter)
+	// inFlight holds a slot for every transport call in progress
+	inFlight = sync.OnceValue(func() chan struct{} {
+		return make(chan struct{}, maxInFlight())
+	})
+)
+
+// newLimiter creates the send rate limiter from EMAIL_RATE and EMAIL_BURST (default: one second of sends)
+func newLimiter() *ratelimit.Limiter {
+	rate, err := strconv.ParseFloat(config.GetEnv("EMAIL_RATE", "14"), 64)
+	if err != nil || rate <= 0 {
+		rate = 14
+	}
+	return ratelimit.New(rate, burstFor(rate))
+}
+
+// burstFor returns EMAIL_BURST, or the number of sends in one second at rate
+func burstFor(rate float64) int {
+	if burst, err := strconv.Atoi(config.GetEnv("EMAIL_BURST")); err == nil && burst > 0 {
+		return burst
+	}
+	return max(1, int(math.Ceil(rate)))
+}
+
+// maxInFlight is the number of transport calls allowed at once
+func maxInFlight() int {
+	n, err := strconv.Atoi(config.GetEnv("EMAIL_MAX_INFLIGHT", "50"))
+	if err != nil || n < 1 {
+		return 50
+	}
+	return n
+}
+
+// syncQuota follows the account's max send rate and daily quota when EMAIL_QUOTA_SYNC is enabled
+func syncQuota(ctx context.Context, transport any) {
+	provider, ok := transport.(quotaProvider)
+	if !ok || config.GetEnv("EMAIL_QUOTA_SYNC", "false") != "true" {
+		return
+	}
+	update := func() {
+		quota, err := provider.Quota(ctx)
+		if err != nil {
+			log.Printf("failed to fetch send quota: %v", err)
+			return
+		}
+		if quota.MaxSendRate > 0 && quota.MaxSendRate != limiter().Rate() {
+			log.Printf("send rate set to %.2f/s from the account quota", quota.MaxSendRate)
+			limiter().SetRate(quota.MaxSendRate)
+			limiter().SetBurst(burstFor(quota.MaxSendRate))
+		}
+		// SES reports -1 for an unlimited daily quota
+		accountQuota.Store(int64(max(quota.Max24HourSend, 0)))
+	}
+	update()
+
+	ticker := time.NewTicker(envDuration("EMAIL_QUOTA_SYNC_INTERVAL", 10*time.Minute))
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+}
@@ -3,24 +3,27 @@ package sender
 import (
 	"aws-ses-sender-go/config"
 	"aws-ses-sender-go/model"
+	"context"
 	"log"
 	"time"
 )
 
-const (
-	bulkSize   = 1000
-	bulkPeriod = 10 * time.Second
-)
+const bulkSize = 1000
 
-// ConsumePostSend updates messages after processing
-func ConsumePostSend() {
+// ConsumePostSend updates messages after processing.
+// When ctx is done it flushes the buffered results and returns, so cancel it only after ConsumeSend has returned.
+func ConsumePostSend(ctx context.Context) {
 	buffer := make([]result, 0, bulkSize)
 
-	ticker := time.NewTicker(bulkPeriod)
+	// Results are written at least every EMAIL_RESULT_FLUSH_INTERVAL; retries are claimable only after that
+	ticker := time.NewTicker(envDuration("EMAIL_RESULT_FLUSH_INTERVAL", 10*time.Second))
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-ctx.Done():
+			flushBuffer(&buffer)
+			return
 		case r := <-resultChan:
 			buffer = append(buffer, r)
 			if len(buffer) >= bulkSize {
@@ -45,14 +48,27 @@ func flushBuffer(buf *[]result) {
 		return
 	}
 
+	// Only the claim a result belongs to may record it: a request cancelled after its lease expired,
+	// or claimed again by another send, must not be overwritten (a retry would re-queue a cancelled request)
+	var stale int
 	for _, m := range *buf {
-		tx.Model(&model.Request{}).
-			Where("id = ?", m.ID).
-			Updates(model.Request{
-				MessageId: m.MessageId,
-				Status:    m.Status,
-				Error:     m.Error,
+		res := tx.Model(&model.Request{}).
+			Where("id = ? AND claim_token = ? AND status = ?", m.ID, m.ClaimToken, model.EmailMessageStatusCreated).
+			Updates(map[string]any{
+				"message_id":      m.MessageId,
+				"status":          m.Status,
+				"error":           m.Error,
+				"attempts":        m.Attempts,
+				"next_attempt_at": m.NextAttemptAt,
+				"lease_until":     nil,
+				"claim_token":     nil,
 			})
+		if res.Error == nil && res.RowsAffected == 0 {
+			stale++
+		}
+	}
+	if stale > 0 {
+		log.Printf("dropped %d results of requests cancelled or claimed again since they were sent", stale)
 	}
 
 	// Commit the transaction
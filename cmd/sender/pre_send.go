@@ -3,34 +3,175 @@ package sender
 import (
 	"aws-ses-sender-go/config"
 	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/address"
+	"aws-ses-sender-go/pkg/mail"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 )
 
-// Request sends an email
-func Request(topicId, to, subject, content string, ctx context.Context) {
+// Request sends an email and returns the created (or, for a repeated idempotency key, the original) request.
+// The returned error is the reason the message was rejected.
+// ctx only bounds validation: once stored, the request is owned by the sender and is stopped only by Cancel.
+func Request(msg Message, ctx context.Context) (Accepted, error) {
+	db := config.GetDB()
+
+	// A known idempotency key returns the original request without sending again
+	if msg.IdempotencyKey != "" {
+		if id, ok := findIdempotent(msg.IdempotencyKey); ok {
+			return Accepted{RequestId: id, Duplicate: true}, nil
+		}
+	}
+
 	// Validate data
-	if to == "" || subject == "" || content == "" {
-		return
+	var templateId *uint
+	var templateVersion int
+	if err := normalizeAddresses(ctx, &msg); err != nil {
+		return Accepted{}, err
+	}
+	if msg.TemplateId != 0 {
+		var tmpl model.Template
+		if err := db.Select("id", "version").First(&tmpl, msg.TemplateId).Error; err != nil {
+			return Accepted{}, fmt.Errorf("template %d not found", msg.TemplateId)
+		}
+		templateId, templateVersion = &tmpl.ID, tmpl.Version
+		if msg.TemplateVersion != 0 {
+			var count int64
+			db.Model(&model.TemplateVersion{}).
+				Where("template_id = ? AND version = ?", tmpl.ID, msg.TemplateVersion).
+				Count(&count)
+			if count == 0 {
+				return Accepted{}, fmt.Errorf("template %d version %d not found", tmpl.ID, msg.TemplateVersion)
+			}
+			templateVersion = msg.TemplateVersion
+		}
+	} else if msg.Subject == "" || msg.Content == "" {
+		return Accepted{}, errors.New("subject and content are required")
+	}
+
+	priority, err := parsePriority(msg.Priority)
+	if err != nil {
+		return Accepted{}, err
+	}
+	attachments, err := newAttachments(msg.Attachments)
+	if err != nil {
+		return Accepted{}, err
+	}
+	sendAt, err := parseSendAt(msg.SendAt, msg.Timezone)
+	if err != nil {
+		return Accepted{}, err
+	}
+	status := model.EmailMessageStatusCreated
+	if sendAt != nil && sendAt.After(time.Now()) {
+		status = model.EmailMessageStatusScheduled
+	}
+
+	// Suppressed addresses are never mailed: copies to them are dropped, and a suppressed recipient
+	// stops the request, which is still stored so the caller can see why it was not sent
+	reasons, err := suppressions(slices.Concat([]string{msg.Email}, msg.Cc, msg.Bcc)...)
+	if err != nil {
+		return Accepted{}, err
+	}
+	msg.Cc = dropSuppressed(msg.Cc, reasons)
+	msg.Bcc = dropSuppressed(msg.Bcc, reasons)
+	var requestError string
+	suppressed := reasons[address.Key(msg.Email)]
+	if suppressed != "" {
+		status = model.EmailMessageStatusStopped
+		requestError = "suppressed: " + suppressed
+	}
+
+	// Generate the plain-text alternative when it was not given (templates are rendered at send time)
+	if msg.Text == "" && templateId == nil {
+		msg.Text = mail.HtmlToText(msg.Content)
 	}
 
 	// Save to database
-	db := config.GetDB()
 	emailMessage := &model.Request{
-		TopicId: topicId,
-		To:      to,
-		Subject: subject,
-		Content: content,
-		Status:  model.EmailMessageStatusCreated,
+		TopicId:  msg.TopicId,
+		From:     msg.From,
+		FromName: msg.FromName,
+		To:       msg.Email,
+		ReplyTo:  msg.ReplyTo,
+		Cc:       msg.Cc,
+		Bcc:      msg.Bcc,
+		Subject:  msg.Subject,
+		Content:  msg.Content,
+		Text:     msg.Text,
+		Status:   status,
+		Priority: priority,
+		Error:    requestError,
+		SendAt:   sendAt,
+		Timezone: msg.Timezone,
+
+		TemplateId:      templateId,
+		TemplateVersion: templateVersion,
+		Data:            msg.Data,
+		Attachments:     attachments,
+	}
+	if msg.IdempotencyKey != "" {
+		emailMessage.IdempotencyKey = &msg.IdempotencyKey
+	}
+	if err := db.WithContext(context.WithoutCancel(ctx)).Create(emailMessage).Error; err != nil {
+		// A concurrent submission with the same key won the unique index
+		if id, ok := findIdempotent(msg.IdempotencyKey); ok {
+			return Accepted{RequestId: id, Duplicate: true}, nil
+		}
+		return Accepted{}, err
 	}
-	db.Create(emailMessage)
 	id := emailMessage.ID
 
-	// Deliver request
-	reqChan <- request{
-		ID:      id,
-		To:      to,
-		Subject: subject,
-		Content: content,
-		Ctx:     ctx,
+	if suppressed != "" {
+		return Accepted{RequestId: id, Suppressed: suppressed}, nil
+	}
+	// Scheduled requests are enqueued later by RunScheduler
+	if emailMessage.Status == model.EmailMessageStatusScheduled {
+		return Accepted{RequestId: id, SendAt: sendAt}, nil
+	}
+
+	// The sender claims the stored request; wake it up instead of waiting for the next poll
+	notify()
+	return Accepted{RequestId: id}, nil
+}
+
+// newRequest converts a stored request into a send request
+func newRequest(m *model.Request) request {
+	var token string
+	if m.ClaimToken != nil {
+		token = *m.ClaimToken
+	}
+	return request{
+		ID:         m.ID,
+		ClaimToken: token,
+		TopicId:    m.TopicId,
+		Attempts:   m.Attempts,
+		From:       m.From,
+		FromName:   m.FromName,
+		To:         m.To,
+		ReplyTo:    m.ReplyTo,
+		Cc:         m.Cc,
+		Bcc:        m.Bcc,
+		Subject:    m.Subject,
+		Content:    m.Content,
+		Text:       m.Text,
+
+		TemplateId:      m.TemplateId,
+		TemplateVersion: m.TemplateVersion,
+		Data:            m.Data,
+		Attachments:     m.Attachments,
+	}
+}
+
+// findIdempotent returns the ID of the request created with the idempotency key
+func findIdempotent(key string) (uint, bool) {
+	if key == "" {
+		return 0, false
+	}
+	var req model.Request
+	if err := config.GetDB().Select("id").Where("idempotency_key = ?", key).First(&req).Error; err != nil {
+		return 0, false
 	}
+	return req.ID, true
 }
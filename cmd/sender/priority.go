@@ -0,0 +1,99 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// lanes lists the priorities in the order they are drained
+var lanes = []int{model.EmailPriorityHigh, model.EmailPriorityNormal, model.EmailPriorityLow}
+
+// parsePriority converts a message priority name into its lane
+func parsePriority(priority string) (int, error) {
+	switch strings.ToLower(priority) {
+	case "", "normal":
+		return model.EmailPriorityNormal, nil
+	case "high":
+		return model.EmailPriorityHigh, nil
+	case "low":
+		return model.EmailPriorityLow, nil
+	default:
+		return 0, fmt.Errorf("invalid priority: %s", priority)
+	}
+}
+
+// laneShares returns the minimum share of each claim per lane (in lanes order) from
+// EMAIL_PRIORITY_SHARES, percentages for high,normal,low (default 70,20,10)
+func laneShares() []float64 {
+	shares := []float64{0.7, 0.2, 0.1}
+	parts := strings.Split(config.GetEnv("EMAIL_PRIORITY_SHARES", "70,20,10"), ",")
+	if len(parts) != len(lanes) {
+		return shares
+	}
+	parsed := make([]float64, len(parts))
+	var total float64
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || n < 0 {
+			return shares
+		}
+		parsed[i], total = n, total+n
+	}
+	if total <= 0 {
+		return shares
+	}
+	for i := range parsed {
+		parsed[i] /= total
+	}
+	return parsed
+}
+
+// laneCredit carries the lane shares across claims too small to give every lane a slot
+var laneCredit struct {
+	sync.Mutex
+	credit []float64
+}
+
+// laneLimits splits a claim of limit requests into the number reserved for each lane.
+// Every lane with a non-zero share gets at least one slot so bulk mail keeps progressing: in each claim
+// when it is large enough, otherwise over successive claims (smooth weighted round-robin by share).
+func laneLimits(limit int) []int {
+	limits := make([]int, len(lanes))
+	shares := laneShares()
+	if limit >= len(lanes) {
+		for i, share := range shares {
+			if share > 0 {
+				limits[i] = max(1, int(float64(limit)*share))
+			}
+		}
+		return limits
+	}
+
+	laneCredit.Lock()
+	defer laneCredit.Unlock()
+	if len(laneCredit.credit) != len(lanes) {
+		laneCredit.credit = make([]float64, len(lanes))
+	}
+	for range limit {
+		best := -1
+		for i, share := range shares {
+			if share <= 0 {
+				continue
+			}
+			laneCredit.credit[i] += share
+			if best < 0 || laneCredit.credit[i] > laneCredit.credit[best] {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		laneCredit.credit[best]--
+		limits[best]++
+	}
+	return limits
+}
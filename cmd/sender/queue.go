@@ -0,0 +1,159 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+// wakeup signals ConsumeSend that new work may be available, avoiding a full poll interval
+var wakeup = make(chan struct{}, 1)
+
+// notify wakes the sender without blocking
+func notify() {
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// leaseTimeout is how long a claimed request stays invisible to other claims.
+// A request whose lease expires without a result (e.g. after a crash) is claimed again.
+func leaseTimeout() time.Duration {
+	d, err := time.ParseDuration(config.GetEnv("EMAIL_LEASE_TIMEOUT", "2m"))
+	if err != nil || d <= 0 {
+		return 2 * time.Minute
+	}
+	return d
+}
+
+// recoverLeases releases the leases of unsent requests left by a previous run
+func recoverLeases() {
+	res := config.GetDB().Model(&model.Request{}).
+		Where("status = ? AND lease_until IS NOT NULL", model.EmailMessageStatusCreated).
+		Updates(map[string]any{"lease_until": nil, "claim_token": nil})
+	if res.Error != nil {
+		log.Printf("failed to recover leased requests: %v", res.Error)
+	} else if res.RowsAffected > 0 {
+		log.Printf("recovered %d unsent requests from a previous run", res.RowsAffected)
+	}
+}
+
+// claim leases up to limit created requests and returns them ready to send, higher priorities first.
+// Each lane first gets its reserved share of the claim; unused capacity goes to the highest lanes with work.
+// Within a lane, topics take turns so a large campaign cannot starve smaller ones; excluded topics are skipped.
+func claim(limit int, exclude []string) ([]request, error) {
+	db := config.GetDB()
+	now := time.Now().UTC()
+	token := newClaimToken()
+
+	remaining := limit
+	take := func(lane, n int) error {
+		if n <= 0 {
+			return nil
+		}
+		claimed, err := claimLane(lane, n, exclude, now, token)
+		remaining -= claimed
+		return err
+	}
+	// Reserved shares first, then whatever is left in priority order
+	reserved := laneLimits(limit)
+	for i, lane := range lanes {
+		if err := take(lane, min(reserved[i], remaining)); err != nil {
+			return nil, err
+		}
+	}
+	for _, lane := range lanes {
+		if err := take(lane, remaining); err != nil {
+			return nil, err
+		}
+	}
+	if remaining == limit {
+		return nil, nil
+	}
+
+	var claimed []model.Request
+	if err := db.Preload("Attachments").
+		Where("claim_token = ?", token).
+		Order("id").
+		Find(&claimed).Error; err != nil {
+		return nil, err
+	}
+	reqs := make([]request, 0, len(claimed))
+	for _, lane := range lanes {
+		var inLane []*model.Request
+		for i := range claimed {
+			if claimed[i].Priority == lane {
+				inLane = append(inLane, &claimed[i])
+			}
+		}
+		for _, m := range interleave(inLane) {
+			reqs = append(reqs, newRequest(m))
+		}
+	}
+	return reqs, nil
+}
+
+// interleave orders requests round-robin across their topics, keeping each topic's order
+func interleave(reqs []*model.Request) []*model.Request {
+	var topicIds []string
+	byTopic := make(map[string][]*model.Request)
+	for _, m := range reqs {
+		if _, ok := byTopic[m.TopicId]; !ok {
+			topicIds = append(topicIds, m.TopicId)
+		}
+		byTopic[m.TopicId] = append(byTopic[m.TopicId], m)
+	}
+	ordered := make([]*model.Request, 0, len(reqs))
+	for len(ordered) < len(reqs) {
+		for _, topicId := range topicIds {
+			if queue := byTopic[topicId]; len(queue) > 0 {
+				ordered = append(ordered, queue[0])
+				byTopic[topicId] = queue[1:]
+			}
+		}
+	}
+	return ordered
+}
+
+// claimLane leases up to limit claimable requests of one priority lane under token,
+// taking the oldest request of every topic before the second oldest of any. Paused topics are skipped.
+func claimLane(priority, limit int, exclude []string, now time.Time, token string) (int, error) {
+	db := config.GetDB()
+	paused := db.Model(&model.Topic{}).Select("topic_id").Where("paused = ?", true)
+	ranked := db.Model(&model.Request{}).
+		Select("id, ROW_NUMBER() OVER (PARTITION BY topic_id ORDER BY id) AS turn").
+		Where("status = ? AND priority = ?", model.EmailMessageStatusCreated, priority).
+		Where("lease_until IS NULL OR lease_until < ?", now).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+		Where("topic_id NOT IN (?)", paused)
+	if len(exclude) > 0 {
+		ranked = ranked.Where("topic_id NOT IN ?", exclude)
+	}
+	candidates := db.Table("(?) AS ranked", ranked).
+		Select("id").
+		Order("turn, id").
+		Limit(limit)
+	res := db.Model(&model.Request{}).
+		Where("id IN (?)", candidates).
+		Updates(map[string]any{"lease_until": now.Add(leaseTimeout()), "claim_token": token})
+	return int(res.RowsAffected), res.Error
+}
+
+// release returns claimed requests that were not dispatched to the queue
+func release(ids []uint) {
+	if err := config.GetDB().Model(&model.Request{}).
+		Where("id IN ? AND status = ?", ids, model.EmailMessageStatusCreated).
+		Updates(map[string]any{"lease_until": nil, "claim_token": nil}).Error; err != nil {
+		log.Printf("failed to release requests: %v", err)
+	}
+}
+
+func newClaimToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,173 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enqueue stores n unsent requests of a topic and priority lane
+func enqueue(t *testing.T, topicId string, priority, n int) []uint {
+	ids := make([]uint, 0, n)
+	for i := range n {
+		m := model.Request{
+			TopicId:  topicId,
+			To:       fmt.Sprintf("%s-%d@example.com", topicId, i),
+			Subject:  "Subject",
+			Content:  "Body",
+			Priority: priority,
+		}
+		require.NoError(t, config.GetDB().Create(&m).Error)
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// claimedPerLane counts the claimed requests of each priority
+func claimedPerLane(t *testing.T, limit, claims int) map[int]int {
+	counts := map[int]int{}
+	for range claims {
+		reqs, err := sender.Claim(limit, nil)
+		require.NoError(t, err)
+		for _, r := range reqs {
+			var m model.Request
+			require.NoError(t, config.GetDB().Select("priority").First(&m, r.ID).Error)
+			counts[m.Priority]++
+		}
+	}
+	return counts
+}
+
+// TestLaneLimits tests that lane shares hold within large claims and across successive small ones
+func TestLaneLimits(t *testing.T) {
+	assert.Equal(t, []int{7, 2, 1}, sender.LaneLimits(10))
+	assert.Equal(t, []int{70, 20, 10}, sender.LaneLimits(100))
+	assert.Equal(t, []int{2, 1, 1}, sender.LaneLimits(3)) // Every lane gets a slot
+
+	for _, limit := range []int{1, 2} {
+		sender.ResetLaneCredit()
+		totals := make([]int, 3)
+		for range 10 {
+			limits := sender.LaneLimits(limit)
+			sum := 0
+			for i, n := range limits {
+				totals[i] += n
+				sum += n
+			}
+			assert.Equal(t, limit, sum)
+		}
+		assert.Equal(t, []int{7 * limit, 2 * limit, limit}, totals, "limit %d", limit)
+	}
+}
+
+// TestClaim_LaneShares tests that every lane gets its share of claims while all lanes have work
+func TestClaim_LaneShares(t *testing.T) {
+	resetQueue(t)
+	enqueue(t, "high", model.EmailPriorityHigh, 30)
+	enqueue(t, "normal", model.EmailPriorityNormal, 30)
+	enqueue(t, "low", model.EmailPriorityLow, 30)
+
+	counts := claimedPerLane(t, 10, 1)
+	assert.Equal(t, map[int]int{model.EmailPriorityHigh: 7, model.EmailPriorityNormal: 2, model.EmailPriorityLow: 1}, counts)
+}
+
+// TestClaim_NoStarvation tests that the low lane progresses when every claim is smaller than the number of lanes
+func TestClaim_NoStarvation(t *testing.T) {
+	for _, limit := range []int{1, 2} {
+		resetQueue(t)
+		sender.ResetLaneCredit()
+		enqueue(t, "high", model.EmailPriorityHigh, 30)
+		enqueue(t, "low", model.EmailPriorityLow, 30)
+
+		// The idle normal lane's share goes to the highest lane with work
+		counts := claimedPerLane(t, limit, 10)
+		assert.Equal(t, map[int]int{model.EmailPriorityHigh: 9 * limit, model.EmailPriorityLow: limit}, counts, "limit %d", limit)
+	}
+}
+
+// TestClaim_UnusedShare tests that capacity reserved for lanes without work is used by the others
+func TestClaim_UnusedShare(t *testing.T) {
+	resetQueue(t)
+	enqueue(t, "low", model.EmailPriorityLow, 30)
+
+	counts := claimedPerLane(t, 10, 1)
+	assert.Equal(t, map[int]int{model.EmailPriorityLow: 10}, counts)
+}
+
+// leased loads the lease of a request
+func leased(t *testing.T, id uint) model.Request {
+	var m model.Request
+	require.NoError(t, config.GetDB().Select("id", "status", "lease_until", "claim_token").First(&m, id).Error)
+	return m
+}
+
+// claimedIds claims up to limit requests and returns their ids
+func claimedIds(t *testing.T, limit int) []uint {
+	reqs, err := sender.Claim(limit, nil)
+	require.NoError(t, err)
+	ids := make([]uint, 0, len(reqs))
+	for _, r := range reqs {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// TestClaim_Lease tests that a claimed request is invisible to other claims until its lease expires
+func TestClaim_Lease(t *testing.T) {
+	t.Setenv("EMAIL_LEASE_TIMEOUT", "200ms")
+	resetQueue(t)
+	ids := enqueue(t, "lease", model.EmailPriorityNormal, 2)
+
+	assert.ElementsMatch(t, ids, claimedIds(t, 10))
+	m := leased(t, ids[0])
+	require.NotNil(t, m.LeaseUntil)
+	require.NotNil(t, m.ClaimToken)
+	assert.Empty(t, claimedIds(t, 10), "leased requests are claimed again")
+
+	// An expired lease (e.g. the sender crashed mid-send) is claimed again under a new token
+	time.Sleep(250 * time.Millisecond)
+	assert.ElementsMatch(t, ids, claimedIds(t, 10))
+	reclaimed := leased(t, ids[0])
+	require.NotNil(t, reclaimed.ClaimToken)
+	assert.NotEqual(t, *m.ClaimToken, *reclaimed.ClaimToken)
+	assert.True(t, reclaimed.LeaseUntil.After(*m.LeaseUntil))
+}
+
+// TestRelease tests that released requests are claimable at once and finished ones are left alone
+func TestRelease(t *testing.T) {
+	resetQueue(t)
+	ids := enqueue(t, "release", model.EmailPriorityNormal, 2)
+	require.Len(t, claimedIds(t, 10), 2)
+	require.NoError(t, config.GetDB().Model(&model.Request{}).Where("id = ?", ids[1]).
+		Update("status", model.EmailMessageStatusSent).Error)
+
+	sender.Release(ids)
+	assert.Nil(t, leased(t, ids[0]).LeaseUntil)
+	assert.NotNil(t, leased(t, ids[1]).LeaseUntil, "a sent request was released")
+	assert.Equal(t, []uint{ids[0]}, claimedIds(t, 10))
+}
+
+// TestRecoverLeases tests that unsent requests leased by a previous run are claimable again on boot
+func TestRecoverLeases(t *testing.T) {
+	resetQueue(t)
+	ids := enqueue(t, "recover", model.EmailPriorityNormal, 3)
+	require.Len(t, claimedIds(t, 10), 3) // Leased for EMAIL_LEASE_TIMEOUT, far beyond this test
+	require.NoError(t, config.GetDB().Model(&model.Request{}).Where("id = ?", ids[2]).
+		Update("status", model.EmailMessageStatusSent).Error)
+	require.Empty(t, claimedIds(t, 10))
+
+	sender.RecoverLeases()
+	for _, id := range ids[:2] {
+		m := leased(t, id)
+		assert.Nil(t, m.LeaseUntil)
+		assert.Nil(t, m.ClaimToken)
+	}
+	assert.NotNil(t, leased(t, ids[2]).LeaseUntil, "a sent request was recovered")
+	assert.ElementsMatch(t, ids[:2], claimedIds(t, 10))
+}
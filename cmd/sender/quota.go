@@ -0,0 +1,130 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/ratelimit"
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// sentWindow counts recipients sent to during the last 24 hours; SES counts its quota per recipient
+var sentWindow = ratelimit.NewWindow(24*time.Hour, time.Minute)
+
+// pendingRecipients counts the recipients of sends in flight, which count against the quota until their results are in
+var pendingRecipients atomic.Int64
+
+// accountQuota is the 24-hour quota reported by the transport (0 when unknown or unlimited)
+var accountQuota atomic.Int64
+
+// paused remembers whether dispatch is held by the daily quota, to log transitions once
+var paused atomic.Bool
+
+// Status is the dispatch state of the sender
+type Status struct {
+	Paused          bool
+	ResumeAt        *time.Time // Estimated, while paused
+	SentLast24Hours int64
+	DailyQuota      int64 // 0 when unlimited
+	PauseAt         int64 // Sent count at which dispatch pauses
+	Rate            float64
+	Burst           int
+	InFlight        int
+}
+
+// GetStatus returns the current dispatch state
+func GetStatus() Status {
+	s := Status{
+		SentLast24Hours: sentWindow.Count(),
+		DailyQuota:      dailyQuota(),
+		PauseAt:         pauseAt(),
+		Rate:            limiter().Rate(),
+		Burst:           limiter().Burst(),
+		InFlight:        len(inFlight()),
+	}
+	if s.PauseAt > 0 && s.SentLast24Hours >= s.PauseAt {
+		resumeAt := time.Now().Add(quotaWait(s.PauseAt)).UTC()
+		s.Paused, s.ResumeAt = true, &resumeAt
+	}
+	return s
+}
+
+// dailyQuota returns EMAIL_DAILY_QUOTA, or the synced account quota when it is not set
+func dailyQuota() int64 {
+	if n, err := strconv.ParseInt(config.GetEnv("EMAIL_DAILY_QUOTA", "0"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return accountQuota.Load()
+}
+
+// pauseAt returns the sent count at which dispatch pauses, EMAIL_DAILY_QUOTA_THRESHOLD of the quota
+func pauseAt() int64 {
+	quota := dailyQuota()
+	if quota <= 0 {
+		return 0
+	}
+	threshold, err := strconv.ParseFloat(config.GetEnv("EMAIL_DAILY_QUOTA_THRESHOLD", "0.95"), 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		threshold = 0.95
+	}
+	return int64(math.Floor(float64(quota) * threshold))
+}
+
+// quotaRemaining returns how many messages may still be dispatched, or how long to wait when none may
+func quotaRemaining() (int, time.Duration) {
+	limit := pauseAt()
+	if limit <= 0 {
+		resume()
+		return math.MaxInt, 0
+	}
+	sent := sentWindow.Count()
+	if sent >= limit {
+		wait := quotaWait(limit)
+		if !paused.Swap(true) {
+			log.Printf("daily send quota reached (%d of %d), pausing for about %s", sent, dailyQuota(), wait.Round(time.Minute))
+		}
+		return 0, wait
+	}
+	resume()
+	if remaining := int(limit - sent - pendingRecipients.Load()); remaining > 0 {
+		return remaining, 0
+	}
+	return 0, 100 * time.Millisecond
+}
+
+// reserveQuota holds n recipients of the daily quota for a send, or reports false when they would exceed it
+func reserveQuota(n int) bool {
+	if limit := pauseAt(); limit > 0 && sentWindow.Count()+pendingRecipients.Load()+int64(n) > limit {
+		return false
+	}
+	pendingRecipients.Add(int64(n))
+	return true
+}
+
+// quotaWait returns how long until fewer than limit messages were sent during the last 24 hours
+func quotaWait(limit int64) time.Duration {
+	return max(sentWindow.Until(limit), time.Second)
+}
+
+func resume() {
+	if paused.Swap(false) {
+		log.Printf("daily send quota has capacity again, resuming")
+	}
+}
+
+// loadSentCount seeds the rolling count with recipients sent to during the last 24 hours
+func loadSentCount() {
+	var sent []model.Request
+	if err := config.GetDB().Select("updated_at", "cc", "bcc").
+		Where("status = ? AND updated_at > ?", model.EmailMessageStatusSent, time.Now().Add(-24*time.Hour)).
+		Find(&sent).Error; err != nil {
+		log.Printf("failed to load the sent count: %v", err)
+		return
+	}
+	for _, m := range sent {
+		sentWindow.AddAt(m.UpdatedAt, int64(1+len(m.Cc)+len(m.Bcc)))
+	}
+}
@@ -0,0 +1,47 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/model"
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuota_CountsRecipients tests that every recipient of a message counts against the daily quota
+func TestQuota_CountsRecipients(t *testing.T) {
+	resetQueue(t)
+	t.Cleanup(func() { resetQueue(t) })
+	// Room for 8 more recipients: the first message's 5 fit, the second's do not
+	t.Setenv("EMAIL_DAILY_QUOTA", strconv.FormatInt(sender.GetStatus().SentLast24Hours+8, 10))
+	t.Setenv("EMAIL_DAILY_QUOTA_THRESHOLD", "1")
+	before := sender.GetStatus().SentLast24Hours
+
+	var ids []uint
+	for i := range 2 {
+		acc, err := sender.Request(sender.Message{
+			TopicId: "quota",
+			Email:   fmt.Sprintf("quota-%d@example.com", i),
+			Cc:      []string{fmt.Sprintf("quota-cc-%d@example.com", i), fmt.Sprintf("quota-cc2-%d@example.com", i)},
+			Bcc:     []string{fmt.Sprintf("quota-bcc-%d@example.com", i), fmt.Sprintf("quota-bcc2-%d@example.com", i)},
+			Subject: "Subject",
+			Content: "<p>Body</p>",
+		}, context.Background())
+		require.NoError(t, err)
+		ids = append(ids, acc.RequestId)
+	}
+	startSender(t)
+
+	stored(t, ids[0], model.EmailMessageStatusSent)
+	assert.Equal(t, before+5, sender.GetStatus().SentLast24Hours)
+
+	// The second message would exceed the quota, so it waits in the queue
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, model.EmailMessageStatusCreated, leased(t, ids[1]).Status)
+	assert.False(t, sentTo("quota-1@example.com")())
+}
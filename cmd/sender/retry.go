@@ -0,0 +1,41 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// maxAttempts returns how many times a request of the topic may be attempted
+func maxAttempts(topicId string) int {
+	if n := topicSettings(topicId).MaxAttempts; n > 0 {
+		return n
+	}
+	n, err := strconv.Atoi(config.GetEnv("EMAIL_MAX_ATTEMPTS", "5"))
+	if err != nil || n < 1 {
+		return 5
+	}
+	return n
+}
+
+// backoff returns the delay before the next attempt: exponential from EMAIL_RETRY_BASE,
+// capped at EMAIL_RETRY_MAX, with the upper half randomized to spread retries out
+func backoff(attempt int) time.Duration {
+	base := envDuration("EMAIL_RETRY_BASE", 30*time.Second)
+	limit := envDuration("EMAIL_RETRY_MAX", time.Hour)
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+	return d/2 + rand.N(d/2+1)
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(config.GetEnv(key, fallback.String()))
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,82 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errThrottled = &mail.Error{Err: errors.New("throttled"), Retryable: true}
+
+// stored returns the request once its result has been recorded with the given status
+func stored(t *testing.T, requestId uint, status int) model.Request {
+	var m model.Request
+	require.Eventually(t, func() bool {
+		m = model.Request{} // NULL columns do not overwrite fields left from an earlier read
+		return config.GetDB().First(&m, requestId).Error == nil && m.Status == status
+	}, 5*time.Second, 20*time.Millisecond)
+	return m
+}
+
+// TestRetry_TransientErrors tests that a retryable error is retried with backoff until the send succeeds
+func TestRetry_TransientErrors(t *testing.T) {
+	resetQueue(t)
+	acc, err := sender.Request(sender.Message{
+		TopicId: "retry",
+		Email:   "retried@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	transport.FailNext(2, errThrottled)
+	startSender(t)
+
+	m := stored(t, acc.RequestId, model.EmailMessageStatusSent)
+	assert.Equal(t, 3, m.Attempts)
+	assert.Empty(t, m.Error)
+	assert.Nil(t, m.LeaseUntil)
+}
+
+// TestRetry_MaxAttempts tests that a request fails after the topic's MaxAttempts retryable errors
+func TestRetry_MaxAttempts(t *testing.T) {
+	resetQueue(t)
+	setTopic(t, model.Topic{TopicId: "retry-limited", MaxAttempts: 3})
+	acc, err := sender.Request(sender.Message{
+		TopicId: "retry-limited",
+		Email:   "exhausted@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	transport.FailNext(3, errThrottled)
+	startSender(t)
+
+	m := stored(t, acc.RequestId, model.EmailMessageStatusFailed)
+	assert.Equal(t, 3, m.Attempts)
+	assert.Equal(t, "throttled", m.Error)
+}
+
+// TestRetry_PermanentError tests that a non-retryable error fails the request on the first attempt
+func TestRetry_PermanentError(t *testing.T) {
+	resetQueue(t)
+	acc, err := sender.Request(sender.Message{
+		TopicId: "retry",
+		Email:   "rejected@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	transport.FailNext(1, &mail.Error{Err: errors.New("message rejected")})
+	startSender(t)
+
+	m := stored(t, acc.RequestId, model.EmailMessageStatusFailed)
+	assert.Equal(t, 1, m.Attempts)
+}
@@ -0,0 +1,100 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Recipient time zones must resolve even without system zoneinfo
+)
+
+// localLayouts are accepted sendAt formats without a UTC offset, interpreted in the message's time zone
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseSendAt parses an absolute RFC 3339 time, or a local time in the given IANA time zone (UTC by default).
+// The result is in UTC because SQLite compares stored times as text.
+func parseSendAt(sendAt, timezone string) (*time.Time, error) {
+	// A bad time zone is rejected even when sendAt carries its own offset
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %s", timezone)
+		}
+	}
+	if sendAt == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, sendAt); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, sendAt, loc); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid sendAt: %s", sendAt)
+}
+
+// schedulerBatchSize returns how many due requests are released per tick
+func schedulerBatchSize() int {
+	n, err := strconv.Atoi(config.GetEnv("SCHEDULER_BATCH_SIZE", "500"))
+	if err != nil || n < 1 {
+		return 500
+	}
+	return n
+}
+
+// RunScheduler periodically releases scheduled requests that are due to the sender until ctx is done
+func RunScheduler(ctx context.Context) {
+	interval, err := time.ParseDuration(config.GetEnv("SCHEDULER_INTERVAL", "10s"))
+	if err != nil {
+		log.Printf("invalid SCHEDULER_INTERVAL, using 10s: %v", err)
+		interval = 10 * time.Second
+	}
+	batch := schedulerBatchSize()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := releaseDue(batch); err != nil {
+				log.Printf("failed to release scheduled requests: %v", err)
+			}
+		}
+	}
+}
+
+// releaseDue moves due scheduled requests to created so that the sender claims them
+func releaseDue(batch int) error {
+	db := config.GetDB()
+	due := db.Model(&model.Request{}).
+		Select("id").
+		Where("status = ? AND send_at <= ?", model.EmailMessageStatusScheduled, time.Now().UTC()).
+		Order("send_at").
+		Limit(batch)
+	res := db.Model(&model.Request{}).
+		Where("id IN (?) AND status = ?", due, model.EmailMessageStatusScheduled).
+		Update("status", model.EmailMessageStatusCreated)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("released %d scheduled messages", res.RowsAffected)
+		notify()
+	}
+	return nil
+}
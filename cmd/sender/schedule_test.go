@@ -0,0 +1,122 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseSendAt tests absolute and local send times and the validation of their time zone
+func TestParseSendAt(t *testing.T) {
+	tests := []struct {
+		sendAt, timezone string
+		want             string // RFC 3339 in UTC; empty for no send time
+		wantErr          bool
+	}{
+		{sendAt: "", want: ""},
+		{sendAt: "2030-01-02T09:00:00+09:00", want: "2030-01-02T00:00:00Z"},
+		{sendAt: "2030-01-02T09:00:00+09:00", timezone: "America/New_York", want: "2030-01-02T00:00:00Z"}, // The offset wins
+		{sendAt: "2030-01-02T09:00", timezone: "Asia/Seoul", want: "2030-01-02T00:00:00Z"},
+		{sendAt: "2030-01-02 09:00:30", want: "2030-01-02T09:00:30Z"},
+		{sendAt: "2030-01-02T09:00:00+09:00", timezone: "Mars/Olympus", wantErr: true},
+		{sendAt: "", timezone: "Mars/Olympus", wantErr: true},
+		{sendAt: "2030-01-02T09:00", timezone: "Mars/Olympus", wantErr: true},
+		{sendAt: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := sender.ParseSendAt(tt.sendAt, tt.timezone)
+		if tt.wantErr {
+			assert.Error(t, err, "%q in %q", tt.sendAt, tt.timezone)
+			continue
+		}
+		require.NoError(t, err, "%q in %q", tt.sendAt, tt.timezone)
+		if tt.want == "" {
+			assert.Nil(t, got)
+			continue
+		}
+		require.NotNil(t, got)
+		assert.Equal(t, tt.want, got.Format(time.RFC3339), "%q in %q", tt.sendAt, tt.timezone)
+	}
+}
+
+// schedule stores a scheduled request due at sendAt
+func schedule(t *testing.T, sendAt time.Time) uint {
+	sendAt = sendAt.UTC()
+	m := model.Request{
+		TopicId: "scheduled",
+		To:      "scheduled@example.com",
+		Subject: "Subject",
+		Content: "Body",
+		Status:  model.EmailMessageStatusScheduled,
+		SendAt:  &sendAt,
+	}
+	require.NoError(t, config.GetDB().Create(&m).Error)
+	return m.ID
+}
+
+// statusOf returns the stored status of a request
+func statusOf(t *testing.T, id uint) int {
+	return leased(t, id).Status
+}
+
+// TestReleaseDue tests that due requests are released earliest first, at most a batch at a time
+func TestReleaseDue(t *testing.T) {
+	resetQueue(t)
+	now := time.Now()
+	later := schedule(t, now.Add(-time.Minute))
+	earlier := schedule(t, now.Add(-time.Hour))
+	future := schedule(t, now.Add(time.Hour))
+
+	require.NoError(t, sender.ReleaseDue(1))
+	assert.Equal(t, model.EmailMessageStatusCreated, statusOf(t, earlier))
+	assert.Equal(t, model.EmailMessageStatusScheduled, statusOf(t, later))
+
+	require.NoError(t, sender.ReleaseDue(10))
+	assert.Equal(t, model.EmailMessageStatusCreated, statusOf(t, later))
+	assert.Equal(t, model.EmailMessageStatusScheduled, statusOf(t, future))
+}
+
+// TestBatchSize tests that an invalid SCHEDULER_BATCH_SIZE falls back to the default
+func TestBatchSize(t *testing.T) {
+	for value, want := range map[string]int{"": 500, "abc": 500, "0": 500, "-5": 500, "20": 20} {
+		t.Setenv("SCHEDULER_BATCH_SIZE", value)
+		assert.Equal(t, want, sender.BatchSize(), value)
+	}
+}
+
+// TestRunScheduler tests that the scheduler releases requests as they fall due and stops with its context
+func TestRunScheduler(t *testing.T) {
+	t.Setenv("SCHEDULER_INTERVAL", "20ms")
+	resetQueue(t)
+	due := schedule(t, time.Now().Add(-time.Second))
+	soon := schedule(t, time.Now().Add(300*time.Millisecond))
+	future := schedule(t, time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sender.RunScheduler(ctx)
+		close(done)
+	}()
+
+	released := func(id uint) func() bool {
+		return func() bool { return statusOf(t, id) == model.EmailMessageStatusCreated }
+	}
+	assert.Eventually(t, released(due), 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, model.EmailMessageStatusScheduled, statusOf(t, soon), "released before it was due")
+	assert.Eventually(t, released(soon), 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+	assert.Equal(t, model.EmailMessageStatusScheduled, statusOf(t, future))
+}
@@ -1,21 +1,79 @@
 package sender
 
-import "context"
+import (
+	"aws-ses-sender-go/model"
+	"time"
+)
+
+// Message is a send request as received from the HTTP API or SQS
+type Message struct {
+	TopicId  string   `json:"topicId"`
+	Email    string   `json:"email"`
+	Subject  string   `json:"subject"`
+	Content  string   `json:"content"`
+	Text     string   `json:"text"`
+	From     string   `json:"from"`
+	FromName string   `json:"fromName"`
+	ReplyTo  []string `json:"replyTo"`
+	Cc       []string `json:"cc"`
+	Bcc      []string `json:"bcc"`
+
+	Priority        string         `json:"priority"`        // high, normal (default) or low
+	IdempotencyKey  string         `json:"idempotencyKey"`  // Repeated keys return the original request instead of sending again
+	SendAt          string         `json:"sendAt"`          // RFC 3339, or local time ("2006-01-02T15:04") in Timezone
+	Timezone        string         `json:"timezone"`        // IANA time zone for a local SendAt (default UTC)
+	TemplateId      uint           `json:"templateId"`      // Renders subject/content from a stored template
+	TemplateVersion int            `json:"templateVersion"` // Pins a template version (default: latest)
+	Data            map[string]any `json:"data"`            // Template variables
+	Attachments     []Attachment   `json:"attachments"`
+}
+
+// Accepted is the outcome of an accepted send request
+type Accepted struct {
+	RequestId  uint
+	Duplicate  bool       // The idempotency key matched an earlier request
+	SendAt     *time.Time // Set when the message was scheduled for later
+	Suppressed string     // Suppression reason of the recipient; the request was stored as stopped
+}
+
+// Attachment is either base64 content or a path relative to BLOB_STORE_DIR
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	ContentId   string `json:"contentId"` // Makes the attachment inline (referenced as cid:...)
+	Content     string `json:"content"`
+	Path        string `json:"path"`
+}
 
 type request struct {
-	ID      uint
-	To      string
-	Subject string
-	Content string
-	Ctx     context.Context
+	ID         uint
+	ClaimToken string
+	TopicId    string
+	Attempts   int
+	From       string
+	FromName   string
+	To         string
+	ReplyTo    []string
+	Cc         []string
+	Bcc        []string
+	Subject    string
+	Content    string
+	Text       string
+
+	TemplateId      *uint
+	TemplateVersion int
+	Data            map[string]any
+	Attachments     []model.Attachment
 }
 
 type result struct {
-	ID        uint
-	MessageId string
-	Status    int
-	Error     string
+	ID            uint
+	ClaimToken    string // Claim the request was sent under; the result is dropped if the request changed since
+	MessageId     string
+	Status        int
+	Error         string
+	Attempts      int
+	NextAttemptAt *time.Time
 }
 
-var reqChan = make(chan request, 1000)
 var resultChan = make(chan result)
@@ -3,67 +3,224 @@ package sender
 import (
 	"aws-ses-sender-go/config"
 	"aws-ses-sender-go/model"
-	"aws-ses-sender-go/pkg/aws"
+	"aws-ses-sender-go/pkg/clock"
+	"aws-ses-sender-go/pkg/mail"
 	"context"
+	"io"
+	"log"
+	"math"
+	netmail "net/mail"
 	"strconv"
+	"sync"
 	"time"
 )
 
-// ConsumeSend consumes the email sending requests
-func ConsumeSend() {
-	rateStr := config.GetEnv("EMAIL_RATE", "14")
-	rate, _ := strconv.Atoi(rateStr)
-	ctx := context.Background()
-	sesClient, err := aws.NewSESClient(ctx)
+// sender returns the From header value, defaulting to EMAIL_SENDER
+func (r *request) sender() string {
+	from := r.From
+	if from == "" {
+		from = config.GetEnv("EMAIL_SENDER")
+	}
+	if r.FromName == "" {
+		return from
+	}
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return from
+	}
+	addr.Name = r.FromName
+	return addr.String()
+}
+
+// recipients returns the number of envelope recipients, which SES counts against its rate and quota
+func (r *request) recipients() int {
+	return 1 + len(r.Cc) + len(r.Bcc)
+}
+
+// message renders the request into a mail message
+func (r *request) message() (*mail.Message, error) {
+	subject, content, text := r.Subject, r.Content, r.Text
+	if r.TemplateId != nil {
+		parsed, err := loadTemplate(*r.TemplateId, r.TemplateVersion)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := parsed.Execute(r.Data)
+		if err != nil {
+			return nil, err
+		}
+		subject, content, text = rendered.Subject, rendered.Html, rendered.Text
+		if text == "" {
+			text = mail.HtmlToText(content)
+		}
+	}
+
+	attachments, err := loadAttachments(r.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add code for the open event at the end of the body
+	serverHost := config.GetEnv("SERVER_HOST", "http://localhost:3000")
+	content += `<img src="` + serverHost + `/v1/events/open/?requestId=` + strconv.Itoa(int(r.ID)) + `">`
+
+	return &mail.Message{
+		From:    r.sender(),
+		To:      []string{r.To},
+		Cc:      r.Cc,
+		Bcc:     r.Bcc,
+		ReplyTo: r.ReplyTo,
+		Subject: subject,
+		Html:    content,
+		Text:    text,
+
+		Attachments: attachments,
+	}, nil
+}
+
+// deliver renders and sends a single request, scheduling a retry for transient failures
+func deliver(transport mail.Transport, m *request) result {
+	attempts := m.Attempts + 1
+	message, err := m.message()
+	if err != nil {
+		// Rendering failed
+		return result{
+			ID:         m.ID,
+			ClaimToken: m.ClaimToken,
+			Status:     model.EmailMessageStatusFailed,
+			Error:      err.Error(),
+			Attempts:   attempts,
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	msgId, err := transport.Send(ctx, message)
+	if err != nil {
+		if mail.IsRetryable(err) && attempts < maxAttempts(m.TopicId) {
+			// Back to created; claimable again once the backoff has passed
+			next := time.Now().UTC().Add(backoff(attempts))
+			return result{
+				ID:            m.ID,
+				ClaimToken:    m.ClaimToken,
+				Status:        model.EmailMessageStatusCreated,
+				Error:         err.Error(),
+				Attempts:      attempts,
+				NextAttemptAt: &next,
+			}
+		}
+		// Sending failed
+		return result{
+			MessageId:  msgId,
+			ID:         m.ID,
+			ClaimToken: m.ClaimToken,
+			Status:     model.EmailMessageStatusFailed,
+			Error:      err.Error(),
+			Attempts:   attempts,
+		}
+	}
+	// Sending succeeded
+	return result{
+		MessageId:  msgId,
+		ID:         m.ID,
+		ClaimToken: m.ClaimToken,
+		Status:     model.EmailMessageStatusSent,
+		Error:      "",
+		Attempts:   attempts,
+	}
+}
+
+// ConsumeSend claims created requests from the database and sends them with the EMAIL_TRANSPORT transport.
+// It returns once ctx is done and the sends in flight have finished.
+func ConsumeSend(ctx context.Context) {
+	transport, err := newTransport(ctx)
 	if err != nil {
 		panic(err)
 	}
+	Consume(ctx, transport)
+}
 
-	for {
-		<-time.After(time.Second / time.Duration(rate))
-		select {
-		case msg := <-reqChan:
-			if msg.Ctx.Err() != nil {
-				// Stop immediately if there is a context error
-				resultChan <- result{
-					MessageId: "",
-					ID:        msg.ID,
-					Status:    model.EmailMessageStatusStopped,
-					Error:     msg.Ctx.Err().Error(),
-				}
+// Consume claims created requests from the database and sends them with transport.
+// When ctx is done it stops claiming, returns unsent claims to the queue and waits for the sends in flight.
+func Consume(ctx context.Context, transport mail.Transport) {
+	pollInterval, err := time.ParseDuration(config.GetEnv("EMAIL_POLL_INTERVAL", "1s"))
+	if err != nil {
+		pollInterval = time.Second
+	}
+	syncQuota(ctx, transport)
+	loadSentCount()
+
+	// Requests claimed by a previous run never got a result; make them claimable again
+	recoverLeases()
+
+	var sending sync.WaitGroup
+	for ctx.Err() == nil {
+		// Hold dispatch while the daily quota is used up
+		remaining, wait := quotaRemaining()
+		if wait > 0 {
+			clock.Sleep(ctx, min(wait, pollInterval))
+			continue
+		}
+
+		// Claim about one second of work at a time so leases stay short
+		reqs, err := claim(min(remaining, max(1, int(math.Ceil(limiter().Rate())))), saturatedTopics())
+		if err != nil {
+			log.Printf("failed to claim requests: %v", err)
+		}
+		if len(reqs) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-wakeup:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		var deferred []uint
+		for _, req := range reqs {
+			// Requests over their topic's budget or the daily quota, or left over at shutdown, go back to the queue
+			if ctx.Err() != nil || !acquireTopic(req.TopicId) {
+				deferred = append(deferred, req.ID)
 				continue
 			}
-			go func(m *request) {
-				// Add code for the open event at the end of the body
-				serverHost := config.GetEnv("SERVER_HOST", "http://localhost:3000")
-				content := m.Content
-				content += `<img src="` + serverHost + `/v1/events/open/?requestId=` + strconv.Itoa(int(m.ID)) + `">`
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-				defer cancel()
-				msgId, err := sesClient.SendEmail(
-					ctx,
-					&m.Subject,
-					&content,
-					&[]string{m.To},
-				)
-				if err != nil {
-					// Sending failed
-					resultChan <- result{
-						MessageId: msgId,
-						ID:        msg.ID,
-						Status:    model.EmailMessageStatusFailed,
-						Error:     err.Error(),
-					}
-					return
-				}
-				// Sending succeeded
-				resultChan <- result{
-					MessageId: msgId,
-					ID:        msg.ID,
-					Status:    model.EmailMessageStatusSent,
-					Error:     "",
+			recipients := req.recipients()
+			if !reserveQuota(recipients) {
+				releaseTopic(req.TopicId)
+				deferred = append(deferred, req.ID)
+				continue
+			}
+			if err := limiter().WaitN(ctx, recipients); err != nil {
+				pendingRecipients.Add(-int64(recipients))
+				releaseTopic(req.TopicId)
+				deferred = append(deferred, req.ID)
+				continue
+			}
+			inFlight() <- struct{}{}
+			sending.Add(1)
+			go func(m request) {
+				defer sending.Done()
+				r := deliver(transport, &m)
+				// Count the send before releasing its reservation so the quota never misses it
+				if r.Status == model.EmailMessageStatusSent {
+					sentWindow.Add(int64(recipients))
 				}
-			}(&msg)
+				pendingRecipients.Add(-int64(recipients))
+				releaseTopic(m.TopicId)
+				<-inFlight()
+				resultChan <- r
+			}(req)
+		}
+		if len(deferred) > 0 {
+			release(deferred)
+			if len(deferred) == len(reqs) {
+				// Every claimed topic is at its budget; give them time to free up
+				clock.Sleep(ctx, 100*time.Millisecond)
+			}
 		}
 	}
+
+	// Let the transport calls in flight finish; their results are still recorded
+	sending.Wait()
+	if closer, ok := transport.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	log.Printf("sender stopped")
 }
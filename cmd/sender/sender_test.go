@@ -0,0 +1,190 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/config/configtest"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var transport = mail.NewMemoryTransport()
+
+func TestMain(m *testing.M) {
+	// Fast enough that the global rate never hides the topic budgets under test
+	_ = os.Setenv("EMAIL_RATE", "1000")
+	_ = os.Setenv("EMAIL_POLL_INTERVAL", "50ms")
+	_ = os.Setenv("EMAIL_RESULT_FLUSH_INTERVAL", "50ms")
+	_ = os.Setenv("EMAIL_RETRY_BASE", "10ms")
+	_ = os.Setenv("EMAIL_RETRY_MAX", "20ms")
+	configtest.Main(m)
+}
+
+// startSender runs the send loop with the in-memory transport until the test ends
+func startSender(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	postSendCtx, stopPostSend := context.WithCancel(context.Background())
+	sendDone, postSendDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		sender.Consume(ctx, transport)
+		close(sendDone)
+	}()
+	go func() {
+		sender.ConsumePostSend(postSendCtx)
+		close(postSendDone)
+	}()
+	// Stop like main: results are recorded after the sends in flight finish
+	t.Cleanup(func() {
+		stop()
+		<-sendDone
+		stopPostSend()
+		<-postSendDone
+	})
+}
+
+// resetQueue deletes the requests left by earlier tests
+func resetQueue(t *testing.T) {
+	require.NoError(t, config.GetDB().Unscoped().Where("1 = 1").Delete(&model.Request{}).Error)
+}
+
+// sentTo reports whether the transport has sent a message to the address
+func sentTo(email string) func() bool {
+	return func() bool {
+		return slices.ContainsFunc(transport.Messages(), func(m mail.Message) bool {
+			return slices.Contains(m.To, email)
+		})
+	}
+}
+
+// TestRequest_SurvivesCancelledContext tests that an accepted message is sent after the submitting context ends
+func TestRequest_SurvivesCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	acc, err := sender.Request(sender.Message{
+		TopicId: "lifecycle",
+		Email:   "after-return@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, ctx)
+	require.NoError(t, err)
+	require.NotZero(t, acc.RequestId)
+
+	// The HTTP request returns and its context is cancelled before the sender picks the message up
+	cancel()
+	startSender(t)
+
+	assert.Eventually(t, sentTo("after-return@example.com"), 5*time.Second, 50*time.Millisecond)
+}
+
+// TestRequest_AcceptsWithDoneContext tests that a context that is already done does not drop the message
+func TestRequest_AcceptsWithDoneContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	acc, err := sender.Request(sender.Message{
+		TopicId: "lifecycle",
+		Email:   "done-context@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, ctx)
+	require.NoError(t, err)
+	startSender(t)
+
+	var stored model.Request
+	require.NoError(t, config.GetDB().First(&stored, acc.RequestId).Error)
+	assert.NotEqual(t, model.EmailMessageStatusStopped, stored.Status)
+	assert.Eventually(t, sentTo("done-context@example.com"), 5*time.Second, 50*time.Millisecond)
+}
+
+// TestCancel tests that only explicit cancellation stops a pending message
+func TestCancel(t *testing.T) {
+	acc, err := sender.Request(sender.Message{
+		TopicId: "lifecycle",
+		Email:   "cancelled@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+		SendAt:  time.Now().Add(time.Hour).Format(time.RFC3339),
+	}, context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, sender.Cancel(acc.RequestId))
+
+	var stored model.Request
+	require.NoError(t, config.GetDB().First(&stored, acc.RequestId).Error)
+	assert.Equal(t, model.EmailMessageStatusStopped, stored.Status)
+	assert.ErrorIs(t, sender.Cancel(acc.RequestId), sender.ErrNotCancellable)
+	assert.ErrorIs(t, sender.Cancel(0), sender.ErrRequestNotFound)
+}
+
+// TestRequest_Suppressed tests that suppressed recipients stop the request and suppressed copies are dropped
+func TestRequest_Suppressed(t *testing.T) {
+	_, err := sender.Suppress("Bounced@Example.com", model.SuppressionReasonBounce, "smtp; 550 5.1.1 user unknown", nil)
+	require.NoError(t, err)
+	_, err = sender.Suppress("complained@example.com", model.SuppressionReasonComplaint, "abuse", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sender.Unsuppress("bounced@example.com")
+		_ = sender.Unsuppress("complained@example.com")
+	})
+
+	acc, err := sender.Request(sender.Message{
+		TopicId: "suppression",
+		Email:   "bounced@example.com",
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, model.SuppressionReasonBounce, acc.Suppressed)
+	var stored model.Request
+	require.NoError(t, config.GetDB().First(&stored, acc.RequestId).Error)
+	assert.Equal(t, model.EmailMessageStatusStopped, stored.Status)
+	assert.Equal(t, "suppressed: bounce", stored.Error)
+
+	acc, err = sender.Request(sender.Message{
+		TopicId: "suppression",
+		Email:   "kept@example.com",
+		Cc:      []string{"complained@example.com", "cc@example.com"},
+		Subject: "Subject",
+		Content: "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, acc.Suppressed)
+	var copied model.Request
+	require.NoError(t, config.GetDB().First(&copied, acc.RequestId).Error)
+	assert.Equal(t, []string{"cc@example.com"}, copied.Cc)
+
+	assert.NoError(t, sender.Unsuppress("BOUNCED@example.com"))
+	assert.ErrorIs(t, sender.Unsuppress("bounced@example.com"), sender.ErrSuppressionNotFound)
+}
+
+// TestRequest_Addresses tests that the per-message sender, reply-to and copies reach the transport
+func TestRequest_Addresses(t *testing.T) {
+	_, err := sender.Request(sender.Message{
+		TopicId:  "addresses",
+		Email:    "addressed@example.com",
+		From:     "team@example.com",
+		FromName: "Team",
+		ReplyTo:  []string{"support@example.com"},
+		Cc:       []string{"copy@example.com", "Copy <second@Example.com>"},
+		Bcc:      []string{"hidden@example.com"},
+		Subject:  "Subject",
+		Content:  "<p>Body</p>",
+	}, context.Background())
+	require.NoError(t, err)
+	startSender(t)
+
+	require.Eventually(t, sentTo("addressed@example.com"), 5*time.Second, 50*time.Millisecond)
+	i := slices.IndexFunc(transport.Messages(), func(m mail.Message) bool { return slices.Contains(m.To, "addressed@example.com") })
+	m := transport.Messages()[i]
+	assert.Equal(t, `"Team" <team@example.com>`, m.From)
+	// Extra addresses are stored normalized, in their RFC 5322 form only when they have a display name
+	assert.Equal(t, []string{"support@example.com"}, m.ReplyTo)
+	assert.Equal(t, []string{"addressed@example.com", "copy@example.com", `"Copy" <second@example.com>`, "hidden@example.com"}, m.Recipients())
+}
@@ -0,0 +1,81 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/address"
+	"errors"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+var ErrSuppressionNotFound = errors.New("address is not suppressed")
+
+// Suppress adds the address to the suppression list, or updates the reason it is on it
+func Suppress(email, reason, detail string, resultId *uint) (*model.Suppression, error) {
+	s := &model.Suppression{
+		Email:    address.Key(email),
+		Reason:   reason,
+		Detail:   detail,
+		ResultId: resultId,
+	}
+	db := config.GetDB()
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.Assignments(map[string]any{"reason": reason, "detail": detail, "result_id": resultId, "updated_at": time.Now()}),
+	}).Create(s).Error
+	if err != nil {
+		return nil, err
+	}
+	// Reload to return the original creation time of an existing entry
+	if err := db.Where("email = ?", s.Email).First(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Unsuppress removes the address from the suppression list
+func Unsuppress(email string) error {
+	res := config.GetDB().Unscoped().
+		Where("email = ?", address.Key(email)).
+		Delete(&model.Suppression{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSuppressionNotFound
+	}
+	return nil
+}
+
+// suppressions returns the suppression reason of each listed address that is suppressed, keyed by address.Key
+func suppressions(addresses ...string) (map[string]string, error) {
+	keys := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		keys = append(keys, address.Key(a))
+	}
+	var rows []model.Suppression
+	if err := config.GetDB().Select("email", "reason").Where("email IN ?", keys).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	reasons := make(map[string]string, len(rows))
+	for _, r := range rows {
+		reasons[r.Email] = r.Reason
+	}
+	return reasons, nil
+}
+
+// dropSuppressed removes suppressed addresses from a copy list
+func dropSuppressed(addresses []string, reasons map[string]string) []string {
+	if len(reasons) == 0 {
+		return addresses
+	}
+	kept := addresses[:0:0]
+	for _, a := range addresses {
+		if _, ok := reasons[address.Key(a)]; !ok {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
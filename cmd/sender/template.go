@@ -0,0 +1,46 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/render"
+	"fmt"
+	"sync"
+)
+
+// templateCache holds parsed template versions; versions are immutable so entries never go stale
+var templateCache sync.Map
+
+// loadTemplate returns the parsed template for a template ID and pinned version
+func loadTemplate(templateId uint, version int) (*render.Template, error) {
+	key := fmt.Sprintf("%d:%d", templateId, version)
+	if cached, ok := templateCache.Load(key); ok {
+		return cached.(*render.Template), nil
+	}
+
+	db := config.GetDB()
+	var subject, html, text string
+	if version == 0 {
+		// Requests created before versioning render the template's current content
+		var tmpl model.Template
+		if err := db.Unscoped().First(&tmpl, templateId).Error; err != nil {
+			return nil, err
+		}
+		subject, html, text = tmpl.Subject, tmpl.Html, tmpl.Text
+	} else {
+		var tv model.TemplateVersion
+		if err := db.Where("template_id = ? AND version = ?", templateId, version).First(&tv).Error; err != nil {
+			return nil, fmt.Errorf("template %d version %d: %w", templateId, version, err)
+		}
+		subject, html, text = tv.Subject, tv.Html, tv.Text
+	}
+
+	parsed, err := render.Parse(subject, html, text)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 {
+		templateCache.Store(key, parsed)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,76 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// editTemplate stores new contents as the template's next version, as the template API does
+func editTemplate(t *testing.T, tmpl *model.Template, subject, html string) {
+	require.NoError(t, config.GetDB().Transaction(func(tx *gorm.DB) error {
+		tmpl.Version++
+		tmpl.Subject, tmpl.Html = subject, html
+		if err := tx.Save(tmpl).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.TemplateVersion{TemplateId: tmpl.ID, Version: tmpl.Version, Subject: subject, Html: html}).Error
+	}))
+}
+
+// lastSentTo returns the latest message the transport has sent to the address
+func lastSentTo(t *testing.T, email string) mail.Message {
+	sent := transport.Messages()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if slices.Contains(sent[i].To, email) {
+			return sent[i]
+		}
+	}
+	t.Fatalf("nothing sent to %s", email)
+	return mail.Message{}
+}
+
+// TestRequest_PinsTemplateVersion tests that a request renders the template version current when it was
+// created, even after the template is edited
+func TestRequest_PinsTemplateVersion(t *testing.T) {
+	tmpl := model.Template{Name: "pinned"}
+	editTemplate(t, &tmpl, "Hello {{.name}}", "<p>Original for {{.name}}</p>")
+	t.Cleanup(func() {
+		config.GetDB().Unscoped().Where("template_id = ?", tmpl.ID).Delete(&model.TemplateVersion{})
+		config.GetDB().Unscoped().Delete(&tmpl)
+	})
+
+	acc, err := sender.Request(sender.Message{
+		TopicId:    "template",
+		Email:      "pinned@example.com",
+		TemplateId: tmpl.ID,
+		Data:       map[string]any{"name": "Ada"},
+	}, context.Background())
+	require.NoError(t, err)
+	editTemplate(t, &tmpl, "Goodbye {{.name}}", "<p>Edited for {{.name}}</p>")
+
+	startSender(t)
+	stored(t, acc.RequestId, model.EmailMessageStatusSent)
+	sent := lastSentTo(t, "pinned@example.com")
+	assert.Equal(t, "Hello Ada", sent.Subject)
+	assert.Contains(t, sent.Html, "<p>Original for Ada</p>")
+
+	// A request created after the edit renders the new version
+	acc, err = sender.Request(sender.Message{
+		TopicId:    "template",
+		Email:      "edited@example.com",
+		TemplateId: tmpl.ID,
+		Data:       map[string]any{"name": "Ada"},
+	}, context.Background())
+	require.NoError(t, err)
+	stored(t, acc.RequestId, model.EmailMessageStatusSent)
+	assert.Equal(t, "Goodbye Ada", lastSentTo(t, "edited@example.com").Subject)
+}
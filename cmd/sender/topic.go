@@ -0,0 +1,116 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"aws-ses-sender-go/pkg/ratelimit"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// topicCacheTTL bounds how long topic setting changes take to reach the sender
+const topicCacheTTL = 10 * time.Second
+
+var topics struct {
+	sync.Mutex
+	loaded time.Time
+	byId   map[string]model.Topic
+}
+
+// topicSettings returns the settings of a topic (the zero value when it has none)
+func topicSettings(topicId string) model.Topic {
+	topics.Lock()
+	defer topics.Unlock()
+	if time.Since(topics.loaded) > topicCacheTTL {
+		var rows []model.Topic
+		if err := config.GetDB().Find(&rows).Error; err != nil {
+			log.Printf("failed to load topic settings: %v", err)
+		} else {
+			topics.byId = make(map[string]model.Topic, len(rows))
+			for _, t := range rows {
+				topics.byId[t.TopicId] = t
+			}
+		}
+		topics.loaded = time.Now()
+	}
+	return topics.byId[topicId]
+}
+
+// topicBudget tracks the send rate and concurrency used by one topic
+type topicBudget struct {
+	limiter  *ratelimit.Limiter // nil without a topic MaxRate
+	inFlight int
+}
+
+var budgets = struct {
+	sync.Mutex
+	byId map[string]*topicBudget
+}{byId: make(map[string]*topicBudget)}
+
+// acquireTopic takes a send slot of the topic when its MaxRate and MaxConcurrency allow one
+func acquireTopic(topicId string) bool {
+	settings := topicSettings(topicId)
+	budgets.Lock()
+	defer budgets.Unlock()
+	b := budgets.byId[topicId]
+	if b == nil {
+		b = &topicBudget{}
+		budgets.byId[topicId] = b
+	}
+	if settings.MaxConcurrency > 0 && b.inFlight >= settings.MaxConcurrency {
+		return false
+	}
+	switch {
+	case settings.MaxRate <= 0:
+		b.limiter = nil
+	case b.limiter == nil:
+		b.limiter = ratelimit.New(settings.MaxRate, max(1, int(math.Ceil(settings.MaxRate))))
+	case b.limiter.Rate() != settings.MaxRate:
+		b.limiter.SetRate(settings.MaxRate)
+		b.limiter.SetBurst(max(1, int(math.Ceil(settings.MaxRate))))
+	}
+	if b.limiter != nil && !b.limiter.Allow() {
+		return false
+	}
+	b.inFlight++
+	return true
+}
+
+// releaseTopic frees the concurrency slot taken by acquireTopic
+func releaseTopic(topicId string) {
+	budgets.Lock()
+	defer budgets.Unlock()
+	if b := budgets.byId[topicId]; b != nil {
+		b.inFlight--
+		if b.idle() {
+			delete(budgets.byId, topicId)
+		}
+	}
+}
+
+// idle reports whether the budget holds no state: nothing in flight and a full (or no) limiter,
+// so dropping it is the same as starting over
+func (b *topicBudget) idle() bool {
+	return b.inFlight <= 0 && (b.limiter == nil || b.limiter.Tokens() >= float64(b.limiter.Burst()))
+}
+
+// saturatedTopics returns the topics that cannot start another send right now, evicting idle budgets
+func saturatedTopics() []string {
+	var saturated []string
+	budgets.Lock()
+	defer budgets.Unlock()
+	for topicId, b := range budgets.byId {
+		if b.idle() {
+			delete(budgets.byId, topicId)
+			continue
+		}
+		settings := topicSettings(topicId)
+		if (settings.MaxConcurrency > 0 && b.inFlight >= settings.MaxConcurrency) ||
+			(b.limiter != nil && b.limiter.Tokens() < 1) {
+			saturated = append(saturated, topicId)
+		}
+	}
+	return saturated
+}
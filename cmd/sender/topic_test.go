@@ -0,0 +1,109 @@
+package sender_test
+
+import (
+	"aws-ses-sender-go/cmd/sender"
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/model"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setTopic stores the delivery settings of a topic and makes the sender see them immediately
+func setTopic(t *testing.T, topic model.Topic) {
+	db := config.GetDB()
+	require.NoError(t, db.Create(&topic).Error)
+	sender.ReloadTopicSettings()
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&topic)
+		sender.ReloadTopicSettings()
+	})
+}
+
+// sentCount counts the messages the transport has sent for a topic queued by enqueue
+func sentCount(topicId string) int {
+	count := 0
+	for _, m := range transport.Messages() {
+		if slices.ContainsFunc(m.To, func(to string) bool { return strings.HasPrefix(to, topicId+"-") }) {
+			count++
+		}
+	}
+	return count
+}
+
+// TestTopicBudget_Concurrency tests that a topic never has more sends in flight than its MaxConcurrency
+func TestTopicBudget_Concurrency(t *testing.T) {
+	setTopic(t, model.Topic{TopicId: "capped", MaxConcurrency: 2})
+
+	require.True(t, sender.AcquireTopic("capped"))
+	require.True(t, sender.AcquireTopic("capped"))
+	assert.False(t, sender.AcquireTopic("capped"))
+	assert.Contains(t, sender.SaturatedTopics(), "capped")
+	assert.True(t, sender.AcquireTopic("other")) // Unlimited
+
+	sender.ReleaseTopic("capped")
+	assert.NotContains(t, sender.SaturatedTopics(), "capped")
+	assert.True(t, sender.AcquireTopic("capped"))
+
+	sender.ReleaseTopic("capped")
+	sender.ReleaseTopic("capped")
+	sender.ReleaseTopic("other")
+}
+
+// TestTopicBudget_Evicted tests that budgets are dropped once idle, so they do not pile up per topic
+func TestTopicBudget_Evicted(t *testing.T) {
+	setTopic(t, model.Topic{TopicId: "throttled", MaxRate: 100})
+
+	require.True(t, sender.AcquireTopic("throttled"))
+	sender.ReleaseTopic("throttled")
+	assert.True(t, sender.HasBudget("throttled")) // Its limiter is still refilling
+
+	time.Sleep(50 * time.Millisecond)
+	sender.SaturatedTopics()
+	assert.False(t, sender.HasBudget("throttled"))
+}
+
+// TestClaim_TopicsTakeTurns tests that a large topic cannot crowd a small one out of a claim
+func TestClaim_TopicsTakeTurns(t *testing.T) {
+	resetQueue(t)
+	enqueue(t, "hot", model.EmailPriorityNormal, 50)
+	enqueue(t, "cold", model.EmailPriorityNormal, 3)
+
+	reqs, err := sender.Claim(8, nil)
+	require.NoError(t, err)
+	var order []string
+	for _, r := range reqs {
+		order = append(order, r.TopicId)
+	}
+	assert.Equal(t, "hot,cold,hot,cold,hot,cold,hot,hot", strings.Join(order, ","))
+
+	// A saturated topic is skipped entirely
+	enqueue(t, "cold", model.EmailPriorityNormal, 2)
+	reqs, err = sender.Claim(8, []string{"hot"})
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	assert.False(t, slices.ContainsFunc(reqs, func(r sender.ClaimedRequest) bool { return r.TopicId == "hot" }))
+}
+
+// TestConsume_HotTopicThrottled tests that a rate-limited topic with a backlog does not hold up other topics
+func TestConsume_HotTopicThrottled(t *testing.T) {
+	resetQueue(t)
+	setTopic(t, model.Topic{TopicId: "campaign", MaxRate: 2})
+	enqueue(t, "campaign", model.EmailPriorityNormal, 20)
+	enqueue(t, "receipts", model.EmailPriorityNormal, 3)
+	campaign, receipts := sentCount("campaign"), sentCount("receipts")
+	start := time.Now()
+	startSender(t)
+
+	require.Eventually(t, func() bool {
+		return sentCount("receipts")-receipts == 3
+	}, 3*time.Second, 20*time.Millisecond)
+
+	// Burst of 2, then 2 per second
+	elapsed := time.Since(start).Seconds()
+	assert.LessOrEqual(t, sentCount("campaign")-campaign, 2+int(2*elapsed)+1)
+}
@@ -0,0 +1,26 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/pkg/aws"
+	"aws-ses-sender-go/pkg/mail"
+	"aws-ses-sender-go/pkg/smtp"
+	"context"
+	"fmt"
+)
+
+// newTransport creates the mail transport selected by EMAIL_TRANSPORT
+func newTransport(ctx context.Context) (mail.Transport, error) {
+	switch name := config.GetEnv("EMAIL_TRANSPORT", "ses"); name {
+	case "ses":
+		return aws.NewSESClient(ctx)
+	case "smtp":
+		return smtp.NewTransport()
+	case "memory":
+		return mail.NewMemoryTransport(), nil
+	case "file":
+		return mail.NewFileTransport(config.GetEnv("EMAIL_TRANSPORT_DIR", "mails"))
+	default:
+		return nil, fmt.Errorf("unknown email transport: %s", name)
+	}
+}
@@ -0,0 +1,69 @@
+package sender
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/pkg/address"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"sync"
+)
+
+// validator normalizes recipient addresses; MX lookups are enabled with EMAIL_VALIDATE_MX=true
+var validator = sync.OnceValue(func() *address.Validator {
+	v := &address.Validator{}
+	if config.GetEnv("EMAIL_VALIDATE_MX", "false") == "true" {
+		v.Resolver = net.DefaultResolver
+	}
+	return v
+})
+
+// normalizeAddresses validates every address of the message and replaces them with their normalized form
+func normalizeAddresses(ctx context.Context, msg *Message) error {
+	if msg.Email == "" {
+		return errors.New("email is required")
+	}
+	to, err := validator().Validate(ctx, msg.Email)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	msg.Email = to.Address
+
+	if msg.From != "" {
+		from, err := address.Normalize(msg.From)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		msg.From = from.Address
+	}
+	for _, field := range []struct {
+		name      string
+		addresses []string
+		checkMX   bool
+	}{
+		{"cc", msg.Cc, true},
+		{"bcc", msg.Bcc, true},
+		{"replyTo", msg.ReplyTo, false},
+	} {
+		for i, a := range field.addresses {
+			var parsed *netmail.Address
+			var err error
+			if field.checkMX {
+				parsed, err = validator().Validate(ctx, a)
+			} else {
+				parsed, err = address.Normalize(a)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", field.name, err)
+			}
+			// Keep the RFC 5322 form only when it carries a display name
+			field.addresses[i] = parsed.Address
+			if parsed.Name != "" {
+				field.addresses[i] = parsed.String()
+			}
+		}
+	}
+	return nil
+}
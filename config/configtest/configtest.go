@@ -0,0 +1,21 @@
+package configtest
+
+import (
+	"aws-ses-sender-go/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Main runs the tests of a package against a throwaway database, opened on first use, and exits
+func Main(m *testing.M) {
+	dir, err := os.MkdirTemp("", "email-test")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("DB_PATH", filepath.Join(dir, "sqlite.db"))
+	code := m.Run()
+	_ = config.CloseDB()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -13,12 +13,18 @@ import (
 var (
 	dbInstance *gorm.DB
 	dbOnce     sync.Once
+	dbModels   []any
 )
 
-// GetDB Database instance
+// RegisterModels registers models to migrate when the database is opened
+func RegisterModels(models ...any) {
+	dbModels = append(dbModels, models...)
+}
+
+// GetDB Database instance (DB_PATH, default sqlite.db), opened and migrated on first use
 func GetDB() *gorm.DB {
 	dbOnce.Do(func() {
-		dbFileName := "sqlite.db"
+		dbFileName := GetEnv("DB_PATH", "sqlite.db")
 		db, err := gorm.Open(sqlite.Open(dbFileName), &gorm.Config{
 			Logger: logger.Default.LogMode(logger.Error),
 		})
@@ -35,7 +41,22 @@ func GetDB() *gorm.DB {
 		sqlDB.SetMaxIdleConns(5)
 		sqlDB.SetConnMaxLifetime(time.Hour)
 
+		for _, m := range dbModels {
+			_ = db.AutoMigrate(m)
+		}
 		dbInstance = db
 	})
 	return dbInstance
 }
+
+// CloseDB closes the database connections
+func CloseDB() error {
+	if dbInstance == nil {
+		return nil
+	}
+	sqlDB, err := dbInstance.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
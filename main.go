@@ -2,9 +2,16 @@ package main
 
 import (
 	"aws-ses-sender-go/api"
+	"aws-ses-sender-go/cmd/collector"
 	"aws-ses-sender-go/cmd/dispatcher"
 	"aws-ses-sender-go/cmd/sender"
 	"aws-ses-sender-go/config"
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/getsentry/sentry-go"
 )
 
@@ -13,13 +20,83 @@ func main() {
 	_ = sentry.Init(sentry.ClientOptions{
 		Dsn: config.GetEnv("SENTRY_DSN"),
 	})
+	defer sentry.Flush(2 * time.Second)
+
+	shutdownTimeout, err := time.ParseDuration(config.GetEnv("SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil {
+		shutdownTimeout = 30 * time.Second
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// One SHUTDOWN_TIMEOUT, counted from the signal, covers both the HTTP server and the send drain
+	var deadline time.Time
+	shuttingDown := make(chan struct{})
+	context.AfterFunc(ctx, func() {
+		deadline = time.Now().Add(shutdownTimeout)
+		close(shuttingDown)
+	})
 
-	// Email Consumer
-	go sender.ConsumeSend()
-	go sender.ConsumePostSend()
+	// Email Consumer; post-send keeps recording results until the sender has drained
+	postSendCtx, stopPostSend := context.WithCancel(context.Background())
+	sendDone := run(func() { sender.ConsumeSend(ctx) })
+	postSendDone := run(func() { sender.ConsumePostSend(postSendCtx) })
+	schedulerDone := run(func() { sender.RunScheduler(ctx) })
 
 	// Message Consumer
-	go dispatcher.Run()
+	dispatcherDone := run(func() { dispatcher.Run(ctx) })
+	// Result Event Consumer
+	collectorDone := run(func() { collector.Run(ctx) })
 	// HTTP Server
-	api.Run()
+	if err := api.Run(ctx, shutdownTimeout); err != nil {
+		log.Printf("HTTP server: %v", err)
+		stop()
+	}
+
+	// Input has stopped; within what is left of the deadline, let the consumers finish what they received
+	// and the sends in flight complete, then record their results
+	<-shuttingDown
+	log.Printf("shutting down, draining (timeout %s)", time.Until(deadline).Round(time.Millisecond))
+	drainCtx, cancelDrain := context.WithDeadline(context.Background(), deadline)
+	defer cancelDrain()
+	drained := true
+	for _, c := range []struct {
+		name string
+		done <-chan struct{}
+	}{
+		{"sends", sendDone},
+		{"SQS dispatcher", dispatcherDone},
+		{"event collector", collectorDone},
+		{"scheduler", schedulerDone},
+	} {
+		select {
+		case <-c.done:
+		case <-drainCtx.Done():
+			log.Printf("%s still running after %s", c.name, shutdownTimeout)
+			drained = false
+		}
+	}
+	stopPostSend()
+	<-postSendDone
+	if !drained {
+		// Closing the database would fail their writes while they run on, and the dispatcher would then
+		// delete SQS messages it could not store. Exiting instead leaves those messages on the queue and
+		// unsent requests leased; both are picked up again after a restart.
+		log.Printf("exiting without closing the database")
+		return
+	}
+	if err := config.CloseDB(); err != nil {
+		log.Printf("failed to close database: %v", err)
+	}
+	log.Printf("shutdown complete")
+}
+
+// run starts f in a goroutine and returns a channel closed when it returns
+func run(f func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	return done
 }
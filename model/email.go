@@ -2,46 +2,129 @@ package model
 
 import (
 	"aws-ses-sender-go/config"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 const (
-	EmailMessageStatusCreated = iota // Creation complete
-	EmailMessageStatusSent           // Sent
-	EmailMessageStatusFailed         // Failed
-	EmailMessageStatusStopped        // Stopped
+	EmailMessageStatusCreated   = iota // Creation complete
+	EmailMessageStatusSent             // Sent
+	EmailMessageStatusFailed           // Failed
+	EmailMessageStatusStopped          // Stopped
+	EmailMessageStatusScheduled        // Waiting for its send time
+)
+
+// Priority lanes; the zero value is the default lane
+const (
+	EmailPriorityNormal = iota // Default
+	EmailPriorityHigh          // Transactional mail, drained first
+	EmailPriorityLow           // Bulk mail
 )
 
 type Request struct {
 	gorm.Model
-	TopicId   string `json:"topic_id" gorm:"index;not null"`
-	MessageId string `json:"message_id" gorm:"index;null;type:varchar(255)"`
-	To        string `json:"to" gorm:"not null;type:varchar(255)"`
-	Subject   string `json:"subject" gorm:"not null;type:varchar(255)"`
-	Content   string `json:"content" gorm:"not null;type:text"`
-	Status    int    `json:"status" gorm:"default:0;not null;type:tinyint"`
-	Error     string `json:"error" gorm:"null;type:varchar(255)"`
+	TopicId   string   `json:"topic_id" gorm:"index;not null"`
+	MessageId string   `json:"message_id" gorm:"index;null;type:varchar(255)"`
+	From      string   `json:"from" gorm:"null;type:varchar(255)"`
+	FromName  string   `json:"from_name" gorm:"null;type:varchar(255)"`
+	To        string   `json:"to" gorm:"not null;type:varchar(255)"`
+	ReplyTo   []string `json:"reply_to" gorm:"null;type:text;serializer:json"`
+	Cc        []string `json:"cc" gorm:"null;type:text;serializer:json"`
+	Bcc       []string `json:"bcc" gorm:"null;type:text;serializer:json"`
+	Subject   string   `json:"subject" gorm:"not null;type:varchar(255)"`
+	Content   string   `json:"content" gorm:"not null;type:text"`
+	Text      string   `json:"text" gorm:"null;type:text"`
+	Status    int      `json:"status" gorm:"default:0;not null;type:tinyint"`
+	Priority  int      `json:"priority" gorm:"index;default:0;not null;type:tinyint"`
+	Error     string   `json:"error" gorm:"null;type:varchar(255)"`
+
+	SendAt          *time.Time     `json:"send_at" gorm:"index;null"`
+	Timezone        string         `json:"timezone" gorm:"null;type:varchar(64)"`
+	Attempts        int            `json:"attempts" gorm:"default:0;not null"`
+	NextAttemptAt   *time.Time     `json:"next_attempt_at" gorm:"index;null"` // Retry not before then
+	LeaseUntil      *time.Time     `json:"lease_until" gorm:"index;null"`     // Claimed by the sender until then
+	ClaimToken      *string        `json:"-" gorm:"index;null;type:varchar(32)"`
+	IdempotencyKey  *string        `json:"idempotency_key" gorm:"uniqueIndex;null;type:varchar(255)"`
+	TemplateId      *uint          `json:"template_id" gorm:"index;null"`
+	TemplateVersion int            `json:"template_version" gorm:"default:0;not null"` // Version pinned at creation
+	Data            map[string]any `json:"data" gorm:"null;type:json;serializer:json"`
+
+	Attachments []Attachment `json:"attachments" gorm:"foreignKey:RequestId"`
 }
 
 func (m *Request) TableName() string {
 	return "email_requests"
 }
 
+// Attachment holds either the decoded content or a path inside the blob store
+type Attachment struct {
+	gorm.Model
+	RequestId   uint   `json:"request_id" gorm:"index;not null"`
+	Filename    string `json:"filename" gorm:"not null;type:varchar(255)"`
+	ContentType string `json:"content_type" gorm:"null;type:varchar(255)"`
+	ContentId   string `json:"content_id" gorm:"null;type:varchar(255)"`
+	Path        string `json:"path" gorm:"null;type:varchar(1024)"`
+	Data        []byte `json:"-" gorm:"null;type:blob"`
+}
+
+func (m *Attachment) TableName() string {
+	return "email_attachments"
+}
+
+// Result is an SES event of a sent request; Status holds the event type
 type Result struct {
 	gorm.Model
-	RequestId uint    `json:"request_id" gorm:"index;not null"`
-	Request   Request `json:"request" gorm:"foreignKey:RequestId;references:ID"`
-	Status    string  `json:"status" gorm:"not null;type:varchar(50)"`
-	Raw       string  `json:"raw" gorm:"null;type:json"`
+	RequestId uint       `json:"request_id" gorm:"index;not null"`
+	Request   Request    `json:"request" gorm:"foreignKey:RequestId;references:ID"`
+	Status    string     `json:"status" gorm:"not null;type:varchar(50)"`
+	MessageId string     `json:"message_id" gorm:"index;null;type:varchar(255)"`
+	EventAt   *time.Time `json:"event_at" gorm:"index;null"`
+
+	BounceType            string `json:"bounce_type" gorm:"index;null;type:varchar(50)"`
+	BounceSubType         string `json:"bounce_sub_type" gorm:"null;type:varchar(50)"`
+	ComplaintFeedbackType string `json:"complaint_feedback_type" gorm:"index;null;type:varchar(50)"`
+	ComplaintSubType      string `json:"complaint_sub_type" gorm:"null;type:varchar(50)"`
+	DelayType             string `json:"delay_type" gorm:"null;type:varchar(50)"`
+	ProcessingTimeMillis  int64  `json:"processing_time_millis" gorm:"default:0;not null"`
+	SmtpResponse          string `json:"smtp_response" gorm:"null;type:varchar(1024)"`
+	ReportingMta          string `json:"reporting_mta" gorm:"null;type:varchar(255)"`
+	Reason                string `json:"reason" gorm:"null;type:text"` // Reject reason or rendering error
+	TemplateName          string `json:"template_name" gorm:"null;type:varchar(255)"`
+	ContactList           string `json:"contact_list" gorm:"null;type:varchar(255)"`
+	Raw                   string `json:"raw" gorm:"null;type:json"`
+
+	Recipients []ResultRecipient `json:"recipients" gorm:"foreignKey:ResultId"`
 }
 
 func (m *Result) TableName() string {
 	return "email_results"
 }
 
+// ResultRecipient is a recipient affected by a bounce, complaint or delivery delay
+type ResultRecipient struct {
+	gorm.Model
+	ResultId       uint   `json:"result_id" gorm:"index;not null"`
+	EmailAddress   string `json:"email_address" gorm:"index;not null;type:varchar(255)"`
+	Action         string `json:"action" gorm:"null;type:varchar(50)"`
+	Status         string `json:"status" gorm:"null;type:varchar(50)"`
+	DiagnosticCode string `json:"diagnostic_code" gorm:"null;type:text"`
+}
+
+func (m *ResultRecipient) TableName() string {
+	return "email_result_recipients"
+}
+
 func init() {
-	db := config.GetDB()
-	_ = db.AutoMigrate(&Request{})
-	_ = db.AutoMigrate(&Result{})
+	config.RegisterModels(
+		&Request{},
+		&Attachment{},
+		&Template{},
+		&TemplateVersion{},
+		&Topic{},
+		&Result{},
+		&ResultRecipient{},
+		&Subscription{},
+		&Suppression{},
+	)
 }
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Subscription statuses
+const (
+	SubscriptionStatusConfirmed    = "confirmed"
+	SubscriptionStatusFailed       = "failed" // Confirmation failed; SNS resends the confirmation
+	SubscriptionStatusUnsubscribed = "unsubscribed"
+)
+
+// Subscription is the SNS subscription delivering result events for a topic
+type Subscription struct {
+	gorm.Model
+	TopicArn        string     `json:"topic_arn" gorm:"uniqueIndex;not null;type:varchar(255)"`
+	SubscriptionArn string     `json:"subscription_arn" gorm:"null;type:varchar(255)"`
+	Status          string     `json:"status" gorm:"not null;type:varchar(50)"`
+	Error           string     `json:"error" gorm:"null;type:varchar(255)"`
+	ConfirmedAt     *time.Time `json:"confirmed_at" gorm:"null"`
+	UnsubscribedAt  *time.Time `json:"unsubscribed_at" gorm:"null"`
+}
+
+func (m *Subscription) TableName() string {
+	return "email_subscriptions"
+}
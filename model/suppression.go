@@ -0,0 +1,23 @@
+package model
+
+import "gorm.io/gorm"
+
+// Suppression reasons
+const (
+	SuppressionReasonBounce    = "bounce"    // Permanent bounce
+	SuppressionReasonComplaint = "complaint" // Recipient marked the mail as spam
+	SuppressionReasonManual    = "manual"
+)
+
+// Suppression is an address that is never mailed again; Email is stored lowercased
+type Suppression struct {
+	gorm.Model
+	Email    string `json:"email" gorm:"uniqueIndex;not null;type:varchar(255)"`
+	Reason   string `json:"reason" gorm:"index;not null;type:varchar(50)"`
+	Detail   string `json:"detail" gorm:"null;type:text"` // e.g. the bounce diagnostic code
+	ResultId *uint  `json:"result_id" gorm:"null"`        // Event that added the address
+}
+
+func (m *Suppression) TableName() string {
+	return "email_suppressions"
+}
@@ -0,0 +1,30 @@
+package model
+
+import "gorm.io/gorm"
+
+type Template struct {
+	gorm.Model
+	Name    string `json:"name" gorm:"not null;type:varchar(255)"`
+	Version int    `json:"version" gorm:"default:0;not null"` // Latest version number
+	Subject string `json:"subject" gorm:"not null;type:varchar(255)"`
+	Html    string `json:"html" gorm:"not null;type:text"`
+	Text    string `json:"text" gorm:"null;type:text"`
+}
+
+func (m *Template) TableName() string {
+	return "email_templates"
+}
+
+// TemplateVersion is an immutable snapshot of a template, created on every change
+type TemplateVersion struct {
+	gorm.Model
+	TemplateId uint   `json:"template_id" gorm:"uniqueIndex:idx_template_version;not null"`
+	Version    int    `json:"version" gorm:"uniqueIndex:idx_template_version;not null"`
+	Subject    string `json:"subject" gorm:"not null;type:varchar(255)"`
+	Html       string `json:"html" gorm:"not null;type:text"`
+	Text       string `json:"text" gorm:"null;type:text"`
+}
+
+func (m *TemplateVersion) TableName() string {
+	return "email_template_versions"
+}
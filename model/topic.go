@@ -0,0 +1,18 @@
+package model
+
+import "gorm.io/gorm"
+
+// Topic holds per-topic delivery settings; topics without a row use the defaults
+type Topic struct {
+	gorm.Model
+	TopicId     string `json:"topic_id" gorm:"uniqueIndex;not null;type:varchar(255)"`
+	MaxAttempts int    `json:"max_attempts" gorm:"default:0;not null"` // 0 uses EMAIL_MAX_ATTEMPTS
+
+	MaxRate        float64 `json:"max_rate" gorm:"default:0;not null"`        // Sends per second, 0 is limited only by EMAIL_RATE
+	MaxConcurrency int     `json:"max_concurrency" gorm:"default:0;not null"` // Sends in flight, 0 is limited only by EMAIL_MAX_INFLIGHT
+	Paused         bool    `json:"paused" gorm:"default:false;not null"`      // The sender skips the topic's requests
+}
+
+func (m *Topic) TableName() string {
+	return "email_topics"
+}
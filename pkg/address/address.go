@@ -0,0 +1,145 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrInvalidSyntax = errors.New("invalid address syntax")
+	ErrInvalidDomain = errors.New("invalid domain")
+	ErrNoMailServer  = errors.New("domain does not accept mail")
+)
+
+// Resolver looks up the DNS records used for MX checks (*net.Resolver satisfies it)
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Normalize parses an RFC 5322 address and converts its domain to lowercase punycode.
+// The display name is kept; use Address for the bare address.
+func Normalize(addr string) (*mail.Address, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(addr))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSyntax, addr)
+	}
+	at := strings.LastIndex(parsed.Address, "@")
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+	for _, r := range local {
+		if r > 127 {
+			return nil, fmt.Errorf("%w: non-ASCII local part is not supported", ErrInvalidSyntax)
+		}
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(ascii, ".") || strings.HasPrefix(domain, "[") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDomain, domain)
+	}
+	parsed.Address = local + "@" + strings.ToLower(ascii)
+	return parsed, nil
+}
+
+// Key returns the lowercased bare form of an address, used to match addresses case-insensitively.
+// An address that does not normalize is only trimmed and lowercased.
+func Key(addr string) string {
+	if parsed, err := Normalize(addr); err == nil {
+		return strings.ToLower(parsed.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(addr))
+}
+
+// Domain returns the domain part of a normalized address
+func Domain(addr string) string {
+	return addr[strings.LastIndex(addr, "@")+1:]
+}
+
+// Validator normalizes addresses and, when a Resolver is set, checks that the domain accepts mail
+type Validator struct {
+	Resolver Resolver
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]domainCheck
+}
+
+type domainCheck struct {
+	err     error
+	expires time.Time
+}
+
+// Validate normalizes the address and verifies its domain
+func (v *Validator) Validate(ctx context.Context, addr string) (*mail.Address, error) {
+	parsed, err := Normalize(addr)
+	if err != nil {
+		return nil, err
+	}
+	if v.Resolver != nil {
+		if err := v.checkDomain(ctx, Domain(parsed.Address)); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// checkDomain looks up MX records (falling back to the implicit MX of RFC 5321), caching the outcome.
+// Only definitive answers reject an address; DNS failures let it through.
+func (v *Validator) checkDomain(ctx context.Context, domain string) error {
+	v.mu.Lock()
+	if c, ok := v.cache[domain]; ok && time.Now().Before(c.expires) {
+		v.mu.Unlock()
+		return c.err
+	}
+	v.mu.Unlock()
+
+	err := v.lookup(ctx, domain)
+	if err != nil && !errors.Is(err, ErrNoMailServer) {
+		return nil
+	}
+
+	ttl := v.CacheTTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	v.mu.Lock()
+	if v.cache == nil {
+		v.cache = map[string]domainCheck{}
+	}
+	v.cache[domain] = domainCheck{err: err, expires: time.Now().Add(ttl)}
+	v.mu.Unlock()
+	return err
+}
+
+func (v *Validator) lookup(ctx context.Context, domain string) error {
+	mxs, err := v.Resolver.LookupMX(ctx, domain)
+	if err == nil {
+		// A single "." record is a null MX (RFC 7505)
+		if len(mxs) == 1 && (mxs[0].Host == "." || mxs[0].Host == "") {
+			return fmt.Errorf("%w: %s publishes a null MX", ErrNoMailServer, domain)
+		}
+		if len(mxs) > 0 {
+			return nil
+		}
+	} else if !isNotFound(err) {
+		return err
+	}
+	if _, err := v.Resolver.LookupHost(ctx, domain); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s has no MX or address records", ErrNoMailServer, domain)
+		}
+		return err
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
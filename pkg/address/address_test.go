@@ -0,0 +1,76 @@
+package address_test
+
+import (
+	"aws-ses-sender-go/pkg/address"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver answers MX lookups from a map; unknown domains do not exist
+type fakeResolver struct {
+	mx      map[string][]*net.MX
+	lookups int
+}
+
+func (r *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	r.lookups++
+	if mx, ok := r.mx[name]; ok {
+		return mx, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// TestNormalize tests lowercasing and punycode conversion of the domain
+func TestNormalize(t *testing.T) {
+	addr, err := address.Normalize(" Tom <Tom.Smith@Bücher.Example> ")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Tom.Smith@xn--bcher-kva.example", addr.Address)
+	assert.Equal(t, "Tom", addr.Name)
+}
+
+// TestNormalize_Invalid tests rejection of malformed addresses
+func TestNormalize_Invalid(t *testing.T) {
+	for _, addr := range []string{"", "user", "user@", "user@localhost", "a b@example.com"} {
+		_, err := address.Normalize(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+// TestKey tests that display names, case and IDN domains reduce to one key
+func TestKey(t *testing.T) {
+	for _, addr := range []string{"tom@bücher.example", " Tom <TOM@Bücher.Example>", "tom@XN--BCHER-KVA.example"} {
+		assert.Equal(t, "tom@xn--bcher-kva.example", address.Key(addr), addr)
+	}
+	assert.Equal(t, "not an address", address.Key(" Not an Address "))
+}
+
+// TestValidate_MX tests MX checks, null MX handling and caching
+func TestValidate_MX(t *testing.T) {
+	resolver := &fakeResolver{mx: map[string][]*net.MX{
+		"example.com": {{Host: "mx.example.com.", Pref: 10}},
+		"null.com":    {{Host: ".", Pref: 0}},
+	}}
+	validator := &address.Validator{Resolver: resolver}
+	ctx := context.TODO()
+
+	_, err := validator.Validate(ctx, "user@example.com")
+	require.NoError(t, err)
+	_, err = validator.Validate(ctx, "other@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolver.lookups, "domain lookups should be cached")
+
+	_, err = validator.Validate(ctx, "user@null.com")
+	assert.ErrorIs(t, err, address.ErrNoMailServer)
+
+	_, err = validator.Validate(ctx, "user@missing.com")
+	assert.ErrorIs(t, err, address.ErrNoMailServer)
+}
@@ -2,17 +2,34 @@ package aws
 
 import (
 	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/pkg/mail"
 	"context"
+	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/smithy-go"
+	"net"
 )
 
+// SESAPI is the subset of the SES v2 client used by SES
+type SESAPI interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+	GetAccount(ctx context.Context, params *sesv2.GetAccountInput, optFns ...func(*sesv2.Options)) (*sesv2.GetAccountOutput, error)
+}
+
+// Quota is the sending quota of the SES account
+type Quota struct {
+	MaxSendRate     float64 // Messages per second
+	Max24HourSend   float64 // Messages per rolling 24 hours
+	SentLast24Hours float64
+}
+
 // SES is a wrapper around the AWS SES client
 type SES struct {
-	Client *sesv2.Client
+	Client SESAPI
 }
 
 // NewSESClient creates an email client
@@ -34,30 +51,97 @@ func NewSESClient(ctx context.Context) (*SES, error) {
 	}, nil
 }
 
-// SendEmail sends an email
-func (s *SES) SendEmail(ctx context.Context, subject, body *string, receivers *[]string) (string, error) {
-	sender := config.GetEnv("EMAIL_SENDER")
+// Send sends an email (implements mail.Transport)
+func (s *SES) Send(ctx context.Context, msg *mail.Message) (string, error) {
+	sender := msg.From
+	if sender == "" {
+		sender = config.GetEnv("EMAIL_SENDER")
+	}
 	input := &sesv2.SendEmailInput{
 		FromEmailAddress: aws.String(sender),
 		Destination: &types.Destination{
-			ToAddresses: *receivers,
+			ToAddresses:  msg.To,
+			CcAddresses:  msg.Cc,
+			BccAddresses: msg.Bcc,
 		},
-		Content: &types.EmailContent{
+	}
+	if len(msg.Attachments) > 0 {
+		// Attachments need a full MIME document, so use the raw content mode
+		m := *msg
+		m.From = sender
+		data, err := m.Bytes()
+		if err != nil {
+			return "", err
+		}
+		input.Content = &types.EmailContent{
+			Raw: &types.RawMessage{Data: data},
+		}
+	} else {
+		input.ReplyToAddresses = msg.ReplyTo
+		input.Content = &types.EmailContent{
 			Simple: &types.Message{
 				Subject: &types.Content{
-					Data: aws.String(*subject),
-				},
-				Body: &types.Body{
-					Html: &types.Content{
-						Data: aws.String(*body),
-					},
+					Data: aws.String(msg.Subject),
 				},
+				Body: &types.Body{},
 			},
-		},
+		}
+		if msg.Html != "" {
+			input.Content.Simple.Body.Html = &types.Content{Data: aws.String(msg.Html)}
+		}
+		if msg.Text != "" {
+			input.Content.Simple.Body.Text = &types.Content{Data: aws.String(msg.Text)}
+		}
 	}
 	result, err := s.Client.SendEmail(ctx, input)
 	if err != nil {
-		return "", err
+		return "", classify(err)
 	}
 	return *result.MessageId, nil
 }
+
+// Quota fetches the account's current sending quota
+func (s *SES) Quota(ctx context.Context) (*Quota, error) {
+	out, err := s.Client.GetAccount(ctx, &sesv2.GetAccountInput{})
+	if err != nil {
+		return nil, err
+	}
+	if out.SendQuota == nil {
+		return nil, errors.New("ses: account has no send quota")
+	}
+	return &Quota{
+		MaxSendRate:     out.SendQuota.MaxSendRate,
+		Max24HourSend:   out.SendQuota.Max24HourSend,
+		SentLast24Hours: out.SendQuota.SentLast24Hours,
+	}, nil
+}
+
+// retryableCodes are SES error codes caused by throttling or service-side trouble
+var retryableCodes = map[string]bool{
+	"TooManyRequestsException": true,
+	"LimitExceededException":   true,
+	"ThrottlingException":      true,
+	"Throttling":               true,
+	"InternalFailure":          true,
+	"ServiceUnavailable":       true,
+}
+
+// classify marks throttling, server-side and connection errors as retryable. Connection errors are only
+// retried when the request never left (dial or DNS failures): after a timeout SES may already have
+// accepted the message, and sending it again would deliver it twice.
+func classify(err error) error {
+	retryable := false
+	var apiErr smithy.APIError
+	var statusErr interface{ HTTPStatusCode() int }
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &apiErr) && (retryableCodes[apiErr.ErrorCode()] || apiErr.ErrorFault() == smithy.FaultServer):
+		retryable = true
+	case errors.As(err, &statusErr) && (statusErr.HTTPStatusCode() == 429 || statusErr.HTTPStatusCode() >= 500):
+		retryable = true
+	case errors.As(err, &dnsErr), errors.As(err, &opErr) && opErr.Op == "dial":
+		retryable = true
+	}
+	return &mail.Error{Err: err, Retryable: retryable}
+}
@@ -0,0 +1,187 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SES event types (eventType for configuration set events, notificationType for identity notifications)
+const (
+	SESEventSend             = "Send"
+	SESEventReject           = "Reject"
+	SESEventBounce           = "Bounce"
+	SESEventComplaint        = "Complaint"
+	SESEventDelivery         = "Delivery"
+	SESEventOpen             = "Open"
+	SESEventClick            = "Click"
+	SESEventRenderingFailure = "Rendering Failure"
+	SESEventDeliveryDelay    = "DeliveryDelay"
+	SESEventSubscription     = "Subscription"
+)
+
+// Bounce types
+const (
+	SESBounceUndetermined = "Undetermined"
+	SESBouncePermanent    = "Permanent"
+	SESBounceTransient    = "Transient"
+)
+
+// SESEvent is an SES event notification; exactly one of the detail fields is set, matching Type
+type SESEvent struct {
+	EventType        string  `json:"eventType"`
+	NotificationType string  `json:"notificationType"`
+	Mail             SESMail `json:"mail"`
+
+	Bounce        *SESBounce           `json:"bounce"`
+	Complaint     *SESComplaint        `json:"complaint"`
+	Delivery      *SESDelivery         `json:"delivery"`
+	Reject        *SESReject           `json:"reject"`
+	Open          *SESOpen             `json:"open"`
+	Click         *SESClick            `json:"click"`
+	Failure       *SESRenderingFailure `json:"failure"`
+	DeliveryDelay *SESDeliveryDelay    `json:"deliveryDelay"`
+	Subscription  *SESSubscription     `json:"subscription"`
+}
+
+// SESMail describes the original message
+type SESMail struct {
+	Timestamp        time.Time           `json:"timestamp"`
+	MessageId        string              `json:"messageId"`
+	Source           string              `json:"source"`
+	SourceArn        string              `json:"sourceArn"`
+	SendingAccountId string              `json:"sendingAccountId"`
+	Destination      []string            `json:"destination"`
+	Tags             map[string][]string `json:"tags"`
+}
+
+// SESBounce is a hard or soft bounce reported by the receiving server
+type SESBounce struct {
+	BounceType        string                `json:"bounceType"`
+	BounceSubType     string                `json:"bounceSubType"`
+	BouncedRecipients []SESRecipientFailure `json:"bouncedRecipients"`
+	Timestamp         time.Time             `json:"timestamp"`
+	FeedbackId        string                `json:"feedbackId"`
+	ReportingMTA      string                `json:"reportingMTA"`
+	RemoteMtaIp       string                `json:"remoteMtaIp"`
+}
+
+// SESRecipientFailure is a recipient of a bounce or delivery delay
+type SESRecipientFailure struct {
+	EmailAddress   string `json:"emailAddress"`
+	Action         string `json:"action"`
+	Status         string `json:"status"`
+	DiagnosticCode string `json:"diagnosticCode"`
+}
+
+// SESComplaint is a complaint reported by the recipient's mailbox provider
+type SESComplaint struct {
+	ComplainedRecipients []struct {
+		EmailAddress string `json:"emailAddress"`
+	} `json:"complainedRecipients"`
+	Timestamp             time.Time `json:"timestamp"`
+	FeedbackId            string    `json:"feedbackId"`
+	ComplaintSubType      string    `json:"complaintSubType"`
+	ComplaintFeedbackType string    `json:"complaintFeedbackType"`
+	UserAgent             string    `json:"userAgent"`
+	ArrivalDate           time.Time `json:"arrivalDate"`
+}
+
+// SESDelivery is a successful delivery to the receiving server
+type SESDelivery struct {
+	Timestamp            time.Time `json:"timestamp"`
+	ProcessingTimeMillis int64     `json:"processingTimeMillis"`
+	Recipients           []string  `json:"recipients"`
+	SmtpResponse         string    `json:"smtpResponse"`
+	ReportingMTA         string    `json:"reportingMTA"`
+	RemoteMtaIp          string    `json:"remoteMtaIp"`
+}
+
+// SESReject is a message SES refused to send, e.g. because it contained a virus
+type SESReject struct {
+	Reason string `json:"reason"`
+}
+
+// SESOpen is an open tracked by SES
+type SESOpen struct {
+	Timestamp time.Time `json:"timestamp"`
+	IpAddress string    `json:"ipAddress"`
+	UserAgent string    `json:"userAgent"`
+}
+
+// SESClick is a link click tracked by SES
+type SESClick struct {
+	Timestamp time.Time           `json:"timestamp"`
+	IpAddress string              `json:"ipAddress"`
+	UserAgent string              `json:"userAgent"`
+	Link      string              `json:"link"`
+	LinkTags  map[string][]string `json:"linkTags"`
+}
+
+// SESRenderingFailure is a template that could not be rendered
+type SESRenderingFailure struct {
+	TemplateName string `json:"templateName"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// SESDeliveryDelay is a temporary delivery failure SES is still retrying
+type SESDeliveryDelay struct {
+	Timestamp         time.Time             `json:"timestamp"`
+	DelayType         string                `json:"delayType"`
+	ExpirationTime    time.Time             `json:"expirationTime"`
+	DelayedRecipients []SESRecipientFailure `json:"delayedRecipients"`
+	ReportingMTA      string                `json:"reportingMTA"`
+}
+
+// SESSubscription is a change of the recipient's contact list preferences
+type SESSubscription struct {
+	ContactList         string          `json:"contactList"`
+	Timestamp           time.Time       `json:"timestamp"`
+	Source              string          `json:"source"`
+	NewTopicPreferences json.RawMessage `json:"newTopicPreferences"`
+	OldTopicPreferences json.RawMessage `json:"oldTopicPreferences"`
+}
+
+// Type returns the event type of either notification format
+func (e *SESEvent) Type() string {
+	if e.EventType != "" {
+		return e.EventType
+	}
+	return e.NotificationType
+}
+
+// Timestamp returns when the event happened (the send time when the event has none)
+func (e *SESEvent) Timestamp() time.Time {
+	switch {
+	case e.Bounce != nil:
+		return e.Bounce.Timestamp
+	case e.Complaint != nil:
+		return e.Complaint.Timestamp
+	case e.Delivery != nil:
+		return e.Delivery.Timestamp
+	case e.Open != nil:
+		return e.Open.Timestamp
+	case e.Click != nil:
+		return e.Click.Timestamp
+	case e.DeliveryDelay != nil:
+		return e.DeliveryDelay.Timestamp
+	case e.Subscription != nil:
+		return e.Subscription.Timestamp
+	}
+	return e.Mail.Timestamp
+}
+
+// ParseSESEvent decodes an SES event notification
+func ParseSESEvent(data []byte) (*SESEvent, error) {
+	var event SESEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	if event.Type() == "" {
+		return nil, errors.New("ses: event has no eventType")
+	}
+	if event.Mail.MessageId == "" {
+		return nil, errors.New("ses: event has no mail.messageId")
+	}
+	return &event, nil
+}
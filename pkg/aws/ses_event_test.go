@@ -0,0 +1,96 @@
+package aws_test
+
+import (
+	"aws-ses-sender-go/pkg/aws"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseSESEvent_Bounce tests that bounce type and per-recipient diagnostics are decoded
+func TestParseSESEvent_Bounce(t *testing.T) {
+	event, err := aws.ParseSESEvent([]byte(`{
+		"eventType": "Bounce",
+		"bounce": {
+			"bounceType": "Permanent",
+			"bounceSubType": "General",
+			"bouncedRecipients": [{
+				"emailAddress": "recipient@example.com",
+				"action": "failed",
+				"status": "5.1.1",
+				"diagnosticCode": "smtp; 550 5.1.1 user unknown"
+			}],
+			"timestamp": "2024-01-02T09:00:01.123Z",
+			"feedbackId": "0102018cc6b7",
+			"reportingMTA": "dsn; e226-55.smtp-out.ap-northeast-2.amazonses.com"
+		},
+		"mail": {"timestamp": "2024-01-02T09:00:00.000Z", "messageId": "0100018c", "destination": ["recipient@example.com"]}
+	}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, aws.SESEventBounce, event.Type())
+	assert.Equal(t, "0100018c", event.Mail.MessageId)
+	require.NotNil(t, event.Bounce)
+	assert.Equal(t, aws.SESBouncePermanent, event.Bounce.BounceType)
+	assert.Equal(t, "General", event.Bounce.BounceSubType)
+	require.Len(t, event.Bounce.BouncedRecipients, 1)
+	assert.Equal(t, "smtp; 550 5.1.1 user unknown", event.Bounce.BouncedRecipients[0].DiagnosticCode)
+	assert.Equal(t, time.Date(2024, 1, 2, 9, 0, 1, 123e6, time.UTC), event.Timestamp())
+}
+
+// TestParseSESEvent_Types tests the detail of the remaining event types, in both notification formats
+func TestParseSESEvent_Types(t *testing.T) {
+	const mail = `"mail": {"timestamp": "2024-01-02T09:00:00.000Z", "messageId": "0100018c"}`
+
+	complaint, err := aws.ParseSESEvent([]byte(`{"notificationType": "Complaint", "complaint": {
+		"complainedRecipients": [{"emailAddress": "recipient@example.com"}],
+		"complaintFeedbackType": "abuse", "complaintSubType": null, "timestamp": "2024-01-02T10:00:00Z"}, ` + mail + `}`))
+	require.NoError(t, err)
+	assert.Equal(t, aws.SESEventComplaint, complaint.Type())
+	assert.Equal(t, "abuse", complaint.Complaint.ComplaintFeedbackType)
+	assert.Equal(t, "recipient@example.com", complaint.Complaint.ComplainedRecipients[0].EmailAddress)
+
+	delay, err := aws.ParseSESEvent([]byte(`{"eventType": "DeliveryDelay", "deliveryDelay": {
+		"delayType": "MailboxFull", "timestamp": "2024-01-02T10:00:00Z",
+		"delayedRecipients": [{"emailAddress": "recipient@example.com", "status": "4.2.2", "diagnosticCode": "smtp; 452 4.2.2 mailbox full"}]}, ` + mail + `}`))
+	require.NoError(t, err)
+	assert.Equal(t, "MailboxFull", delay.DeliveryDelay.DelayType)
+	assert.Equal(t, "4.2.2", delay.DeliveryDelay.DelayedRecipients[0].Status)
+
+	delivery, err := aws.ParseSESEvent([]byte(`{"eventType": "Delivery", "delivery": {
+		"processingTimeMillis": 546, "smtpResponse": "250 ok", "timestamp": "2024-01-02T09:00:01Z"}, ` + mail + `}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(546), delivery.Delivery.ProcessingTimeMillis)
+
+	reject, err := aws.ParseSESEvent([]byte(`{"eventType": "Reject", "reject": {"reason": "Bad content"}, ` + mail + `}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Bad content", reject.Reject.Reason)
+	assert.Equal(t, time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), reject.Timestamp()) // Falls back to the send time
+
+	failure, err := aws.ParseSESEvent([]byte(`{"eventType": "Rendering Failure", "failure": {
+		"templateName": "welcome", "errorMessage": "Attribute 'name' is not present"}, ` + mail + `}`))
+	require.NoError(t, err)
+	assert.Equal(t, aws.SESEventRenderingFailure, failure.Type())
+	assert.Equal(t, "welcome", failure.Failure.TemplateName)
+
+	subscription, err := aws.ParseSESEvent([]byte(`{"eventType": "Subscription", "subscription": {
+		"contactList": "news", "timestamp": "2024-01-02T11:00:00Z", "source": "UnsubscribeHeader",
+		"newTopicPreferences": {"unsubscribeAll": true}}, ` + mail + `}`))
+	require.NoError(t, err)
+	assert.Equal(t, "news", subscription.Subscription.ContactList)
+	assert.JSONEq(t, `{"unsubscribeAll": true}`, string(subscription.Subscription.NewTopicPreferences))
+}
+
+// TestParseSESEvent_Invalid tests that events without a type or message ID are rejected
+func TestParseSESEvent_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"mail": {"messageId": "0100018c"}}`,
+		`{"eventType": "Delivery", "mail": {}}`,
+	} {
+		_, err := aws.ParseSESEvent([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
@@ -2,14 +2,19 @@ package aws_test
 
 import (
 	"aws-ses-sender-go/pkg/aws"
+	"aws-ses-sender-go/pkg/mail"
 	"context"
 	"errors"
+	"net"
+	"os"
 	"reflect"
 	"testing"
 
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/smithy-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,8 +31,14 @@ func (m *MockSESClient) SendEmail(ctx context.Context, params *sesv2.SendEmailIn
 	return args.Get(0).(*sesv2.SendEmailOutput), args.Error(1)
 }
 
-// TestGetClient_Success tests if the GetClient function successfully returns a client
-func TestGetClient_Success(t *testing.T) {
+// GetAccount method is the mock implementation of the GetAccount method
+func (m *MockSESClient) GetAccount(ctx context.Context, params *sesv2.GetAccountInput, optFns ...func(*sesv2.Options)) (*sesv2.GetAccountOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	return args.Get(0).(*sesv2.GetAccountOutput), args.Error(1)
+}
+
+// TestNewSESClient_Success tests if the NewSESClient function successfully returns a client
+func TestNewSESClient_Success(t *testing.T) {
 	// Create context
 	ctx := context.TODO()
 
@@ -42,15 +53,15 @@ func TestGetClient_Success(t *testing.T) {
 	expectedClient := sesv2.NewFromConfig(expectedCfg)
 
 	// Execute test
-	client, err := aws.GetClient(ctx)
+	client, err := aws.NewSESClient(ctx)
 
 	// Validate results
 	require.NoError(t, err, "unexpected error while getting AWS client")
-	assert.Equal(t, reflect.TypeOf(expectedClient), reflect.TypeOf(client), "client type mismatch")
+	assert.Equal(t, reflect.TypeOf(expectedClient), reflect.TypeOf(client.Client), "client type mismatch")
 }
 
-// TestSendSESEmail_Success tests if the SendSESEmail function successfully sends an email
-func TestSendSESEmail_Success(t *testing.T) {
+// TestSend_Success tests if the Send method successfully sends an email
+func TestSend_Success(t *testing.T) {
 	// Set up mock client and input data
 	mockClient := new(MockSESClient)
 	ctx := context.TODO()
@@ -65,7 +76,8 @@ func TestSendSESEmail_Success(t *testing.T) {
 	}, nil)
 
 	// Execute test
-	messageId, err := aws.SendSESEmail(mockClient, ctx, &subject, &body, &receivers)
+	ses := &aws.SES{Client: mockClient}
+	messageId, err := ses.Send(ctx, &mail.Message{To: receivers, Subject: subject, Html: body})
 
 	// Validate results
 	require.NoError(t, err, "unexpected error while sending email")
@@ -73,8 +85,8 @@ func TestSendSESEmail_Success(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
-// TestSendSESEmail_SendError tests if the SendSESEmail function returns an error when an email send operation fails
-func TestSendSESEmail_SendError(t *testing.T) {
+// TestSend_SendError tests if the Send method returns an error when an email send operation fails
+func TestSend_SendError(t *testing.T) {
 	// Set up mock client and input data
 	mockClient := new(MockSESClient)
 	ctx := context.TODO()
@@ -87,10 +99,104 @@ func TestSendSESEmail_SendError(t *testing.T) {
 	mockClient.On("SendEmail", ctx, mock.AnythingOfType("*sesv2.SendEmailInput"), mock.AnythingOfType("[]func(*sesv2.Options)")).Return((*sesv2.SendEmailOutput)(nil), expectedError)
 
 	// Execute test
-	_, err := aws.SendSESEmail(mockClient, ctx, &subject, &body, &receivers)
+	ses := &aws.SES{Client: mockClient}
+	_, err := ses.Send(ctx, &mail.Message{To: receivers, Subject: subject, Html: body})
 
 	// Validate results
 	require.Error(t, err, "expected error but got nil")
 	assert.EqualError(t, err, expectedError.Error(), "error message mismatch")
 	mockClient.AssertExpectations(t)
 }
+
+// TestSend_ThrottlingIsRetryable tests that throttling errors are classified as retryable
+func TestSend_ThrottlingIsRetryable(t *testing.T) {
+	mockClient := new(MockSESClient)
+	ctx := context.TODO()
+	throttled := &smithy.GenericAPIError{Code: "TooManyRequestsException", Message: "Maximum sending rate exceeded."}
+	rejected := &smithy.GenericAPIError{Code: "MessageRejected", Message: "Email address is not verified."}
+
+	mockClient.On("SendEmail", ctx, mock.AnythingOfType("*sesv2.SendEmailInput"), mock.AnythingOfType("[]func(*sesv2.Options)")).Return((*sesv2.SendEmailOutput)(nil), throttled).Once()
+	mockClient.On("SendEmail", ctx, mock.AnythingOfType("*sesv2.SendEmailInput"), mock.AnythingOfType("[]func(*sesv2.Options)")).Return((*sesv2.SendEmailOutput)(nil), rejected).Once()
+
+	ses := &aws.SES{Client: mockClient}
+	msg := &mail.Message{To: []string{"test@example.com"}, Subject: "Test Subject", Html: "Test Body"}
+	_, throttledErr := ses.Send(ctx, msg)
+	_, rejectedErr := ses.Send(ctx, msg)
+
+	assert.True(t, mail.IsRetryable(throttledErr), "throttling should be retryable")
+	assert.False(t, mail.IsRetryable(rejectedErr), "rejection should be permanent")
+	mockClient.AssertExpectations(t)
+}
+
+// TestSend_AmbiguousErrorsAreNotRetried tests that only connection errors before the request is sent are retryable
+func TestSend_AmbiguousErrorsAreNotRetried(t *testing.T) {
+	ctx := context.TODO()
+	msg := &mail.Message{To: []string{"test@example.com"}, Subject: "Test Subject", Html: "Test Body"}
+	for _, tc := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"dial", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"dns", &net.DNSError{Err: "no such host", Name: "email.ap-northeast-2.amazonaws.com"}, true},
+		{"deadline", context.DeadlineExceeded, false},
+		{"read timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, false},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, false},
+	} {
+		mockClient := new(MockSESClient)
+		mockClient.On("SendEmail", ctx, mock.AnythingOfType("*sesv2.SendEmailInput"), mock.AnythingOfType("[]func(*sesv2.Options)")).Return((*sesv2.SendEmailOutput)(nil), tc.err).Once()
+
+		_, err := (&aws.SES{Client: mockClient}).Send(ctx, msg)
+
+		require.Error(t, err, tc.name)
+		assert.Equal(t, tc.retryable, mail.IsRetryable(err), tc.name)
+	}
+}
+
+// TestQuota_Success tests if the Quota method returns the account send quota
+func TestQuota_Success(t *testing.T) {
+	mockClient := new(MockSESClient)
+	ctx := context.TODO()
+
+	mockClient.On("GetAccount", ctx, mock.AnythingOfType("*sesv2.GetAccountInput"), mock.AnythingOfType("[]func(*sesv2.Options)")).Return(&sesv2.GetAccountOutput{
+		SendQuota: &types.SendQuota{MaxSendRate: 50, Max24HourSend: 200000, SentLast24Hours: 1200},
+	}, nil)
+
+	ses := &aws.SES{Client: mockClient}
+	quota, err := ses.Quota(ctx)
+
+	require.NoError(t, err, "unexpected error while fetching quota")
+	assert.Equal(t, &aws.Quota{MaxSendRate: 50, Max24HourSend: 200000, SentLast24Hours: 1200}, quota)
+	mockClient.AssertExpectations(t)
+}
+
+// TestSend_Destination tests that the sender, reply-to and every envelope recipient reach SES
+func TestSend_Destination(t *testing.T) {
+	mockClient := new(MockSESClient)
+	ctx := context.TODO()
+	messageId := "12345"
+
+	mockClient.On("SendEmail", ctx, mock.MatchedBy(func(input *sesv2.SendEmailInput) bool {
+		return *input.FromEmailAddress == "Team <team@example.com>" &&
+			reflect.DeepEqual(input.Destination, &types.Destination{
+				ToAddresses:  []string{"user@example.com"},
+				CcAddresses:  []string{"copy@example.com"},
+				BccAddresses: []string{"hidden@example.com"},
+			}) &&
+			reflect.DeepEqual(input.ReplyToAddresses, []string{"support@example.com"})
+	}), mock.AnythingOfType("[]func(*sesv2.Options)")).Return(&sesv2.SendEmailOutput{MessageId: &messageId}, nil)
+
+	ses := &aws.SES{Client: mockClient}
+	_, err := ses.Send(ctx, &mail.Message{
+		From:    "Team <team@example.com>",
+		To:      []string{"user@example.com"},
+		Cc:      []string{"copy@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		ReplyTo: []string{"support@example.com"},
+		Subject: "Subject",
+		Html:    "Body",
+	})
+
+	require.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
@@ -70,6 +70,17 @@ func (s *SQS) GetOrCreateQueue(ctx context.Context) (*string, error) {
 	return out.QueueUrl, nil
 }
 
+// GetQueueUrl looks up the URL of an existing SQS queue
+func (s *SQS) GetQueueUrl(ctx context.Context, name string) (*string, error) {
+	out, err := s.Client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+		QueueName: &name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.QueueUrl, nil
+}
+
 // SendMessage sends a message to the SQS queue
 func (s *SQS) SendMessage(ctx context.Context, queueUrl string, body string) (*sqs.SendMessageOutput, error) {
 	input := &sqs.SendMessageInput{
@@ -86,6 +97,9 @@ func (s *SQS) ReceiveMessages(ctx context.Context, queueUrl *string, maxMessages
 		MaxNumberOfMessages: maxMessages,
 		WaitTimeSeconds:     waitTimeSeconds,
 		VisibilityTimeout:   30,
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+		},
 	})
 	if err != nil {
 		return nil, err
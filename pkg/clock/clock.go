@@ -0,0 +1,14 @@
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep pauses for d, returning early when ctx is done
+func Sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
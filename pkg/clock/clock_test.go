@@ -0,0 +1,23 @@
+package clock_test
+
+import (
+	"aws-ses-sender-go/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSleep tests that a sleep lasts its duration unless the context ends first
+func TestSleep(t *testing.T) {
+	start := time.Now()
+	clock.Sleep(context.Background(), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	clock.Sleep(ctx, time.Hour)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileTransport writes every message as a JSON file into a directory
+type FileTransport struct {
+	Dir string
+	seq atomic.Uint64
+}
+
+// NewFileTransport creates a file-backed transport, creating the directory if needed
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileTransport{Dir: dir}, nil
+}
+
+// Send writes the message to disk and returns the file name as the message ID
+func (t *FileTransport) Send(ctx context.Context, msg *Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%d-%d", time.Now().UnixNano(), t.seq.Add(1))
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(t.Dir, id+".json"), data, 0o644); err != nil {
+		return "", err
+	}
+	return id, nil
+}
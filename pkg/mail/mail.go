@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"context"
+	"errors"
+)
+
+// Message is a provider independent email message
+type Message struct {
+	MessageId   string
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     []string
+	Subject     string
+	Html        string
+	Text        string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a message; a ContentId makes it an inline part
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentId   string
+	Data        []byte
+}
+
+// Recipients returns every envelope recipient of the message
+func (m *Message) Recipients() []string {
+	rcpts := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	rcpts = append(rcpts, m.To...)
+	rcpts = append(rcpts, m.Cc...)
+	return append(rcpts, m.Bcc...)
+}
+
+// Transport delivers a message and returns the provider's message ID
+type Transport interface {
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// Error is a send error classified by the transport
+type Error struct {
+	Err       error
+	Retryable bool // Transient failure (throttling, 5xx, no connection) that may succeed later without a duplicate
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether a send error is transient.
+// Errors may implement Retryable() bool; anything else is permanent. Unclassified timeouts are not retried:
+// the provider may have accepted the message before the response was lost, and a retry would send it twice.
+func IsRetryable(err error) bool {
+	var mailErr *Error
+	if errors.As(err, &mailErr) {
+		return mailErr.Retryable
+	}
+	var classified interface{ Retryable() bool }
+	return errors.As(err, &classified) && classified.Retryable()
+}
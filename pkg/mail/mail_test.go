@@ -0,0 +1,128 @@
+package mail_test
+
+import (
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHtmlToText tests the plain-text conversion of an HTML body
+func TestHtmlToText(t *testing.T) {
+	body := `<html><head><style>p { color: red; }</style></head><body>` +
+		`<h1>Hi &amp; welcome</h1><p>Click <a href="https://example.com">here</a> now.</p>` +
+		`<ul><li>one</li><li>two</li></ul></body></html>`
+
+	text := mail.HtmlToText(body)
+
+	assert.Equal(t, "Hi & welcome\n\nClick here (https://example.com) now.\n\n- one\n- two", text)
+}
+
+// TestBytes_Alternative tests that messages with both bodies are sent as multipart/alternative
+func TestBytes_Alternative(t *testing.T) {
+	msg := &mail.Message{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Hello",
+		Html:    "<p>Hello</p>",
+		Text:    "Hello",
+	}
+
+	data, err := msg.Bytes()
+
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Content-Type: multipart/alternative; boundary=")
+	assert.Contains(t, string(data), "Content-Type: text/plain; charset=utf-8")
+	assert.Contains(t, string(data), "Content-Type: text/html; charset=utf-8")
+}
+
+// TestBytes_Attachments tests that inline and regular attachments are nested correctly
+func TestBytes_Attachments(t *testing.T) {
+	msg := &mail.Message{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		Subject: "Invoice",
+		Html:    `<p>See attached</p><img src="cid:logo">`,
+		Attachments: []mail.Attachment{
+			{Filename: "logo.png", ContentId: "logo", Data: []byte("png")},
+			{Filename: "invoice.pdf", Data: []byte("%PDF-1.4")},
+		},
+	}
+
+	data, err := msg.Bytes()
+
+	require.NoError(t, err)
+	body := string(data)
+	assert.Contains(t, body, "Content-Type: multipart/mixed; boundary=")
+	assert.Contains(t, body, "Content-Type: multipart/related; boundary=")
+	assert.Contains(t, body, "Content-Id: <logo>")
+	assert.Contains(t, body, `Content-Disposition: attachment; filename=invoice.pdf`)
+	assert.Contains(t, body, "Content-Type: application/pdf; name=invoice.pdf")
+	assert.NotContains(t, body, "hidden@example.com")
+}
+
+// TestIsRetryable tests that only errors classified as transient are retried
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, mail.IsRetryable(fmt.Errorf("send: %w", &mail.Error{Err: errors.New("throttled"), Retryable: true})))
+	assert.False(t, mail.IsRetryable(&mail.Error{Err: errors.New("rejected")}))
+	assert.False(t, mail.IsRetryable(errors.New("invalid address")))
+
+	// A timeout may come after the message was accepted
+	assert.False(t, mail.IsRetryable(context.DeadlineExceeded))
+	assert.False(t, mail.IsRetryable(&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}))
+}
+
+// TestBytes_Headers tests that sender, reply-to and copy recipients are rendered as headers and Bcc is not
+func TestBytes_Headers(t *testing.T) {
+	msg := &mail.Message{
+		From:    "홍길동 <sender@example.com>",
+		To:      []string{"user@example.com"},
+		Cc:      []string{"Copy <copy@example.com>", "second@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		ReplyTo: []string{"Support <support@example.com>"},
+		Subject: "Hello",
+		Text:    "Hello",
+	}
+
+	data, err := msg.Bytes()
+
+	require.NoError(t, err)
+	header, _, _ := strings.Cut(string(data), "\r\n\r\n")
+	assert.Contains(t, header, "From: =?utf-8?q?=ED=99=8D=EA=B8=B8=EB=8F=99?= <sender@example.com>\r\n")
+	assert.Contains(t, header, "To: <user@example.com>\r\n")
+	assert.Contains(t, header, `Cc: "Copy" <copy@example.com>, <second@example.com>`+"\r\n")
+	assert.Contains(t, header, `Reply-To: "Support" <support@example.com>`+"\r\n")
+	assert.NotContains(t, string(data), "hidden@example.com")
+}
+
+// TestBytes_OptionalHeaders tests that empty reply-to and copy lists leave their headers out
+func TestBytes_OptionalHeaders(t *testing.T) {
+	msg := &mail.Message{From: "sender@example.com", To: []string{"user@example.com"}, Subject: "Hello", Text: "Hello"}
+
+	data, err := msg.Bytes()
+
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Cc:")
+	assert.NotContains(t, string(data), "Reply-To:")
+}
+
+// TestRecipients tests that the envelope includes To, Cc and Bcc but not the reply-to addresses
+func TestRecipients(t *testing.T) {
+	msg := &mail.Message{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		Cc:      []string{"copy@example.com"},
+		Bcc:     []string{"hidden@example.com", "audit@example.com"},
+		ReplyTo: []string{"support@example.com"},
+	}
+
+	assert.Equal(t, []string{"user@example.com", "copy@example.com", "hidden@example.com", "audit@example.com"}, msg.Recipients())
+}
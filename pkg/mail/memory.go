@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryTransport keeps sent messages in memory (useful for local runs and tests)
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+	failures []error
+}
+
+// NewMemoryTransport creates an in-memory transport
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+// Send stores the message and returns a generated message ID
+func (t *MemoryTransport) Send(ctx context.Context, msg *Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.failures) > 0 {
+		err := t.failures[0]
+		t.failures = t.failures[1:]
+		return "", err
+	}
+	t.messages = append(t.messages, *msg)
+	return fmt.Sprintf("memory-%d", len(t.messages)), nil
+}
+
+// Messages returns a copy of the messages sent so far
+func (t *MemoryTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.messages...)
+}
+
+// FailNext makes the next n sends return err instead of sending
+func (t *MemoryTransport) FailNext(n int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for range n {
+		t.failures = append(t.failures, err)
+	}
+}
@@ -0,0 +1,230 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// entity is a MIME entity: its header fields and a function writing its content
+type entity struct {
+	header textproto.MIMEHeader
+	write  func(w io.Writer) error
+}
+
+// NewMessageId generates an RFC 5322 Message-ID (without angle brackets) for the sender's domain
+func NewMessageId(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	return randomHex(16) + "@" + domain
+}
+
+// Bytes renders the message as an RFC 5322 document
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	messageId := m.MessageId
+	if messageId == "" {
+		messageId = NewMessageId(m.From)
+	}
+
+	writeHeader(&buf, "From", encodeAddressList([]string{m.From}))
+	writeHeader(&buf, "To", encodeAddressList(m.To))
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", encodeAddressList(m.Cc))
+	}
+	if len(m.ReplyTo) > 0 {
+		writeHeader(&buf, "Reply-To", encodeAddressList(m.ReplyTo))
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", "<"+messageId+">")
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	root := m.rootEntity()
+	for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if v := root.header.Get(key); v != "" {
+			writeHeader(&buf, key, v)
+		}
+	}
+	buf.WriteString("\r\n")
+	if err := root.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// rootEntity nests the body as multipart/mixed > multipart/related > multipart/alternative as needed
+func (m *Message) rootEntity() entity {
+	var inline, attached []entity
+	for _, a := range m.Attachments {
+		if a.ContentId != "" {
+			inline = append(inline, a.entity())
+		} else {
+			attached = append(attached, a.entity())
+		}
+	}
+
+	root := m.bodyEntity()
+	if len(inline) > 0 {
+		root = multipartEntity("related", append([]entity{root}, inline...))
+	}
+	if len(attached) > 0 {
+		root = multipartEntity("mixed", append([]entity{root}, attached...))
+	}
+	return root
+}
+
+// bodyEntity returns the text/HTML body, using multipart/alternative when both versions exist
+func (m *Message) bodyEntity() entity {
+	if m.Html == "" {
+		return textEntity("text/plain", m.Text)
+	}
+	if m.Text == "" {
+		return textEntity("text/html", m.Html)
+	}
+	return multipartEntity("alternative", []entity{
+		textEntity("text/plain", m.Text),
+		textEntity("text/html", m.Html),
+	})
+}
+
+func textEntity(contentType, body string) entity {
+	return entity{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		write: func(w io.Writer) error {
+			return writeQuotedPrintable(w, body)
+		},
+	}
+}
+
+func multipartEntity(subtype string, parts []entity) entity {
+	boundary := randomHex(15)
+	return entity{
+		header: textproto.MIMEHeader{
+			"Content-Type": {"multipart/" + subtype + "; boundary=" + boundary},
+		},
+		write: func(w io.Writer) error {
+			mw := multipart.NewWriter(w)
+			if err := mw.SetBoundary(boundary); err != nil {
+				return err
+			}
+			for _, p := range parts {
+				pw, err := mw.CreatePart(p.header)
+				if err != nil {
+					return err
+				}
+				if err := p.write(pw); err != nil {
+					return err
+				}
+			}
+			return mw.Close()
+		},
+	}
+}
+
+// entity renders the attachment as a base64 encoded part
+func (a *Attachment) entity() entity {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	header := textproto.MIMEHeader{
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if a.ContentId != "" {
+		disposition = "inline"
+		header.Set("Content-ID", "<"+a.ContentId+">")
+	}
+	if a.Filename != "" {
+		contentType = withParam(contentType, "name", a.Filename)
+		disposition = withParam(disposition, "filename", a.Filename)
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", disposition)
+
+	data := a.Data
+	return entity{
+		header: header,
+		write: func(w io.Writer) error {
+			encoded := base64.StdEncoding.EncodeToString(data)
+			for len(encoded) > 76 {
+				if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+					return err
+				}
+				encoded = encoded[76:]
+			}
+			_, err := io.WriteString(w, encoded+"\r\n")
+			return err
+		},
+	}
+}
+
+// withParam appends a parameter to a media type, encoding it when necessary
+func withParam(mediaType, key, value string) string {
+	base, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		base, params = mediaType, map[string]string{}
+	}
+	params[key] = value
+	if formatted := mime.FormatMediaType(base, params); formatted != "" {
+		return formatted
+	}
+	return base
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+}
+
+// encodeAddressList encodes display names so that non-ASCII names survive transport
+func encodeAddressList(addrs []string) string {
+	encoded := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if parsed, err := mail.ParseAddress(a); err == nil {
+			encoded = append(encoded, parsed.String())
+		} else {
+			encoded = append(encoded, a)
+		}
+	}
+	return strings.Join(encoded, ", ")
+}
+
+func writeQuotedPrintable(out io.Writer, body string) error {
+	w := quotedprintable.NewWriter(out)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\r\n")
+	return err
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
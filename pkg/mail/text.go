@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockTags are elements rendered on their own line in the plain-text version
+var blockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "hr": true, "blockquote": true, "pre": true,
+}
+
+// HtmlToText converts an HTML body into a readable plain-text alternative
+func HtmlToText(body string) string {
+	var sb strings.Builder
+	var href string
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return body
+			}
+			return tidyText(sb.String())
+		case html.TextToken:
+			if skip == 0 {
+				sb.WriteString(strings.ReplaceAll(string(z.Text()), "\n", " "))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			switch tag {
+			case "script", "style", "head", "title":
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+				continue
+			case "a":
+				if tt == html.StartTagToken {
+					href = ""
+					for hasAttr {
+						var key, val []byte
+						key, val, hasAttr = z.TagAttr()
+						if string(key) == "href" {
+							href = string(val)
+						}
+					}
+				} else if tt == html.EndTagToken && href != "" && !strings.HasPrefix(href, "#") {
+					sb.WriteString(" (" + href + ")")
+					href = ""
+				}
+			case "li":
+				if tt == html.StartTagToken {
+					sb.WriteString("\n- ")
+					continue
+				}
+			}
+			if blockTags[tag] {
+				sb.WriteString("\n")
+			}
+		}
+	}
+}
+
+// tidyText collapses whitespace within lines and runs of blank lines
+func tidyText(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,92 @@
+package ratelimit_test
+
+import (
+	"aws-ses-sender-go/pkg/ratelimit"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAllow_Burst tests that a full bucket allows exactly burst tokens at once
+func TestAllow_Burst(t *testing.T) {
+	l := ratelimit.New(1, 3)
+
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+}
+
+// TestWait_Refill tests that Wait blocks until a token is refilled
+func TestWait_Refill(t *testing.T) {
+	l := ratelimit.New(20, 1)
+	require.True(t, l.Allow())
+
+	start := time.Now()
+	err := l.Wait(context.Background())
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+// TestWaitN tests that WaitN takes n tokens, and that more than burst puts the bucket in debt
+func TestWaitN(t *testing.T) {
+	l := ratelimit.New(100, 5)
+
+	require.NoError(t, l.WaitN(context.Background(), 3))
+	assert.InDelta(t, 2, l.Tokens(), 0.5)
+
+	// Waits for a full bucket, then leaves it 3 tokens short
+	start := time.Now()
+	require.NoError(t, l.WaitN(context.Background(), 8))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.InDelta(t, -3, l.Tokens(), 0.5)
+	assert.False(t, l.Allow())
+
+	// The debt is paid off before the next token
+	start = time.Now()
+	require.NoError(t, l.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+// TestWait_Cancel tests that Wait returns when the context is done
+func TestWait_Cancel(t *testing.T) {
+	l := ratelimit.New(0.1, 1)
+	require.True(t, l.Allow())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := l.Wait(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestSetRate tests that a rate change applies to later tokens
+func TestSetRate(t *testing.T) {
+	l := ratelimit.New(0.1, 1)
+	require.True(t, l.Allow())
+	l.SetRate(100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, l.Wait(ctx))
+	assert.Equal(t, 100.0, l.Rate())
+}
+
+// TestWindow_Rolling tests that events leave the window after the period
+func TestWindow_Rolling(t *testing.T) {
+	w := ratelimit.NewWindow(time.Hour, time.Minute)
+	w.AddAt(time.Now().Add(-2*time.Hour), 5)
+	w.AddAt(time.Now().Add(-50*time.Minute), 3)
+	w.Add(2)
+
+	assert.Equal(t, int64(5), w.Count())
+	assert.Zero(t, w.Until(6))
+	until := w.Until(3)
+	assert.Greater(t, until, 9*time.Minute)
+	assert.LessOrEqual(t, until, 11*time.Minute)
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Window counts events over a rolling period, in buckets of resolution
+type Window struct {
+	mu         sync.Mutex
+	resolution time.Duration
+	counts     []int64
+	slots      []int64 // Slot number each bucket currently holds
+}
+
+// NewWindow creates a rolling counter over period
+func NewWindow(period, resolution time.Duration) *Window {
+	n := max(1, int(period/resolution))
+	return &Window{
+		resolution: resolution,
+		counts:     make([]int64, n),
+		slots:      make([]int64, n),
+	}
+}
+
+// Add counts n events now
+func (w *Window) Add(n int64) {
+	w.AddAt(time.Now(), n)
+}
+
+// AddAt counts n events at t; events older than the window are ignored
+func (w *Window) AddAt(t time.Time, n int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	slot := w.slot(t)
+	if slot <= w.slot(time.Now())-int64(len(w.counts)) {
+		return
+	}
+	i := w.index(slot)
+	if w.slots[i] != slot {
+		w.slots[i], w.counts[i] = slot, 0
+	}
+	w.counts[i] += n
+}
+
+// Count returns the number of events within the window
+func (w *Window) Count() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var total int64
+	oldest := w.slot(time.Now()) - int64(len(w.counts))
+	for i, slot := range w.slots {
+		if slot > oldest {
+			total += w.counts[i]
+		}
+	}
+	return total
+}
+
+// Until returns how long until fewer than limit events remain within the window
+func (w *Window) Until(limit int64) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	current := w.slot(now)
+	n := int64(len(w.counts))
+
+	var total int64
+	for i, slot := range w.slots {
+		if slot > current-n {
+			total += w.counts[i]
+		}
+	}
+	// Expire buckets oldest first until the count drops below limit
+	for slot := current - n + 1; slot <= current && total >= limit; slot++ {
+		if i := w.index(slot); w.slots[i] == slot {
+			total -= w.counts[i]
+		}
+		if total < limit {
+			expires := time.Unix(0, (slot+n)*int64(w.resolution))
+			return expires.Sub(now)
+		}
+	}
+	if total >= limit {
+		return time.Duration(n) * w.resolution
+	}
+	return 0
+}
+
+func (w *Window) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(w.resolution)
+}
+
+func (w *Window) index(slot int64) int {
+	return int(slot % int64(len(w.counts)))
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"bytes"
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+	"text/template/parse"
+)
+
+// Template is a parsed subject/HTML/text template set
+type Template struct {
+	subject *textTemplate.Template
+	html    *htmlTemplate.Template
+	text    *textTemplate.Template
+
+	variables []string
+}
+
+// Result is a rendered message
+type Result struct {
+	Subject string
+	Html    string
+	Text    string
+}
+
+// Parse parses the subject and text with text/template and the HTML with html/template (contextual escaping)
+func Parse(subject, html, text string) (*Template, error) {
+	t := &Template{}
+	var err error
+	if t.subject, err = textTemplate.New("subject").Option("missingkey=error").Parse(subject); err != nil {
+		return nil, err
+	}
+	if t.html, err = htmlTemplate.New("html").Option("missingkey=error").Parse(html); err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if t.text, err = textTemplate.New("text").Option("missingkey=error").Parse(text); err != nil {
+			return nil, err
+		}
+	}
+
+	var textTree *parse.Tree
+	if t.text != nil {
+		textTree = t.text.Tree
+	}
+	t.variables = collectVariables(t.subject.Tree, t.html.Tree, textTree)
+	return t, nil
+}
+
+// Execute renders the template with the given data; a missing variable is an error
+func (t *Template) Execute(data map[string]any) (*Result, error) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	var subject, html, text bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if t.text != nil {
+		if err := t.text.Execute(&text, data); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Subject: subject.String(), Html: html.String(), Text: text.String()}, nil
+}
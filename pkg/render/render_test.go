@@ -0,0 +1,47 @@
+package render_test
+
+import (
+	"aws-ses-sender-go/pkg/render"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExecute_EscapesHtml tests that variables are escaped in HTML but not in the subject
+func TestExecute_EscapesHtml(t *testing.T) {
+	tmpl, err := render.Parse("Hi {{.name}}", "<p>Hi {{.name}}</p>", "Hi {{.name}}")
+	require.NoError(t, err)
+
+	result, err := tmpl.Execute(map[string]any{"name": "<Tom & Jerry>"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Hi <Tom & Jerry>", result.Subject)
+	assert.Equal(t, "<p>Hi &lt;Tom &amp; Jerry&gt;</p>", result.Html)
+	assert.Equal(t, "Hi <Tom & Jerry>", result.Text)
+}
+
+// TestExecute_MissingVariable tests that a missing variable fails rendering
+func TestExecute_MissingVariable(t *testing.T) {
+	tmpl, err := render.Parse("Hi {{.name}}", "<p>Hi</p>", "")
+	require.NoError(t, err)
+
+	_, err = tmpl.Execute(map[string]any{})
+
+	assert.Error(t, err)
+}
+
+// TestMissing tests that only root level variables absent from the data are reported
+func TestMissing(t *testing.T) {
+	tmpl, err := render.Parse(
+		"{{.title}}",
+		`<p>{{.name}}</p>{{range .items}}<li>{{.label}} {{$.currency}}</li>{{end}}`,
+		"{{with .coupon}}{{.code}}{{end}}",
+	)
+	require.NoError(t, err)
+
+	missing := tmpl.Missing(map[string]any{"name": "Tom", "items": []any{}})
+
+	assert.Equal(t, []string{"coupon", "currency", "items", "name", "title"}, tmpl.Variables())
+	assert.Equal(t, []string{"coupon", "currency", "title"}, missing)
+}
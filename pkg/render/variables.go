@@ -0,0 +1,88 @@
+package render
+
+import (
+	"sort"
+	"text/template/parse"
+)
+
+// Variables returns the top-level data keys referenced by the template
+func (t *Template) Variables() []string {
+	return t.variables
+}
+
+// Missing returns the referenced variables absent from data
+func (t *Template) Missing(data map[string]any) []string {
+	missing := make([]string, 0)
+	for _, v := range t.variables {
+		if _, ok := data[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// collectVariables walks the parse trees and gathers the fields read from the root data
+func collectVariables(trees ...*parse.Tree) []string {
+	seen := map[string]bool{}
+	for _, tree := range trees {
+		if tree != nil && tree.Root != nil {
+			walk(tree.Root, true, seen)
+		}
+	}
+	vars := make([]string, 0, len(seen))
+	for v := range seen {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// walk visits a node; atRoot is false inside range/with bodies where the dot no longer is the root data
+func walk(node parse.Node, atRoot bool, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walk(child, atRoot, seen)
+		}
+	case *parse.ActionNode:
+		walk(n.Pipe, atRoot, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walk(cmd, atRoot, seen)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walk(arg, atRoot, seen)
+		}
+	case *parse.FieldNode:
+		if atRoot {
+			seen[n.Ident[0]] = true
+		}
+	case *parse.ChainNode:
+		walk(n.Node, atRoot, seen)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			seen[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		walk(n.Pipe, atRoot, seen)
+		walk(n.List, atRoot, seen)
+		walk(n.ElseList, atRoot, seen)
+	case *parse.RangeNode:
+		walk(n.Pipe, atRoot, seen)
+		walk(n.List, false, seen)
+		walk(n.ElseList, atRoot, seen)
+	case *parse.WithNode:
+		walk(n.Pipe, atRoot, seen)
+		walk(n.List, false, seen)
+		walk(n.ElseList, atRoot, seen)
+	case *parse.TemplateNode:
+		walk(n.Pipe, atRoot, seen)
+	}
+}
@@ -0,0 +1,226 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// conn is a single SMTP session
+type conn struct {
+	nc       net.Conn
+	text     *textproto.Conn
+	ext      map[string]string
+	lastUsed time.Time
+}
+
+// dial connects, negotiates TLS and authenticates
+func dial(ctx context.Context, cfg Config) (*conn, error) {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	var nc net.Conn
+	var err error
+	if cfg.Security == SecurityTLS {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: cfg.TLSConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{nc: nc, text: textproto.NewConn(nc)}
+	c.setDeadline(ctx, cfg.Timeout)
+	if err := c.handshake(cfg); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *conn) handshake(cfg Config) error {
+	if _, err := c.readResponse(220); err != nil {
+		return err
+	}
+	if err := c.hello(cfg.HeloName); err != nil {
+		return err
+	}
+	if cfg.Security == SecurityStartTLS {
+		if _, ok := c.ext["STARTTLS"]; !ok {
+			return errors.New("smtp: server does not support STARTTLS")
+		}
+		if _, err := c.cmd(220, "STARTTLS"); err != nil {
+			return err
+		}
+		c.nc = tls.Client(c.nc, cfg.TLSConfig)
+		c.text = textproto.NewConn(c.nc)
+		if err := c.hello(cfg.HeloName); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		return c.auth(cfg)
+	}
+	return nil
+}
+
+// hello sends EHLO (falling back to HELO) and records the advertised extensions
+func (c *conn) hello(name string) error {
+	msg, err := c.cmd(250, "EHLO %s", name)
+	if err != nil {
+		if _, err := c.cmd(250, "HELO %s", name); err != nil {
+			return err
+		}
+		c.ext = map[string]string{}
+		return nil
+	}
+	c.ext = map[string]string{}
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[1:] {
+		key, args, _ := strings.Cut(line, " ")
+		c.ext[strings.ToUpper(key)] = args
+	}
+	return nil
+}
+
+func (c *conn) auth(cfg Config) error {
+	mechanism := strings.ToUpper(cfg.Auth)
+	if mechanism == "" {
+		advertised := strings.Fields(strings.ToUpper(c.ext["AUTH"]))
+		for _, m := range []string{"PLAIN", "LOGIN"} {
+			for _, a := range advertised {
+				if a == m && mechanism == "" {
+					mechanism = m
+				}
+			}
+		}
+	}
+	encode := base64.StdEncoding.EncodeToString
+	switch mechanism {
+	case "PLAIN":
+		_, err := c.cmd(235, "AUTH PLAIN %s", encode([]byte("\x00"+cfg.Username+"\x00"+cfg.Password)))
+		return err
+	case "LOGIN":
+		if _, err := c.cmd(334, "AUTH LOGIN"); err != nil {
+			return err
+		}
+		if _, err := c.cmd(334, "%s", encode([]byte(cfg.Username))); err != nil {
+			return err
+		}
+		_, err := c.cmd(235, "%s", encode([]byte(cfg.Password)))
+		return err
+	default:
+		return fmt.Errorf("smtp: no supported authentication mechanism (server offers %q)", c.ext["AUTH"])
+	}
+}
+
+// send runs one mail transaction, pipelining the envelope when the server allows it
+func (c *conn) send(from string, rcpts []string, data []byte) error {
+	if len(rcpts) == 0 {
+		return errors.New("smtp: no recipients")
+	}
+	if _, ok := c.ext["PIPELINING"]; ok {
+		if err := c.sendEnvelopePipelined(from, rcpts); err != nil {
+			return err
+		}
+	} else {
+		if _, err := c.cmd(250, "MAIL FROM:<%s>", from); err != nil {
+			return err
+		}
+		for _, r := range rcpts {
+			if _, err := c.cmd(25, "RCPT TO:<%s>", r); err != nil {
+				return err
+			}
+		}
+		if _, err := c.cmd(354, "DATA"); err != nil {
+			return err
+		}
+	}
+
+	w := c.text.DotWriter()
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	_, err := c.readResponse(250)
+	return err
+}
+
+// sendEnvelopePipelined writes MAIL, RCPT and DATA in one batch (RFC 2920) and then reads every reply
+func (c *conn) sendEnvelopePipelined(from string, rcpts []string) error {
+	w := c.text.W
+	fmt.Fprintf(w, "MAIL FROM:<%s>\r\n", from)
+	for _, r := range rcpts {
+		fmt.Fprintf(w, "RCPT TO:<%s>\r\n", r)
+	}
+	w.WriteString("DATA\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, firstErr := c.readResponse(250)
+	for range rcpts {
+		if _, err := c.readResponse(25); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	_, dataErr := c.readResponse(354)
+	if firstErr == nil {
+		return dataErr
+	}
+	if dataErr == nil {
+		// The server is waiting for content although a recipient was refused;
+		// dropping the connection is the only way to abort without delivering.
+		c.close()
+	}
+	return firstErr
+}
+
+// reset aborts any pending transaction and checks that the session is still alive
+func (c *conn) reset() error {
+	_, err := c.cmd(250, "RSET")
+	return err
+}
+
+func (c *conn) quit() {
+	_, _ = c.cmd(221, "QUIT")
+	c.close()
+}
+
+func (c *conn) close() {
+	_ = c.text.Close()
+}
+
+func (c *conn) setDeadline(ctx context.Context, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.nc.SetDeadline(deadline)
+}
+
+func (c *conn) cmd(expectCode int, format string, args ...any) (string, error) {
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	return c.readResponse(expectCode)
+}
+
+// readResponse reads a reply and converts negative replies into *Error
+func (c *conn) readResponse(expectCode int) (string, error) {
+	_, msg, err := c.text.ReadResponse(expectCode)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return msg, &Error{Code: protoErr.Code, Message: protoErr.Msg}
+	}
+	return msg, err
+}
@@ -0,0 +1,18 @@
+package smtp
+
+import "fmt"
+
+// Error is a negative reply from the SMTP server
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Retryable reports whether the reply is a transient (4xx) failure
+func (e *Error) Retryable() bool {
+	return e.Code >= 400 && e.Code < 500
+}
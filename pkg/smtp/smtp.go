@@ -0,0 +1,199 @@
+package smtp
+
+import (
+	"aws-ses-sender-go/config"
+	"aws-ses-sender-go/pkg/mail"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"strconv"
+	"time"
+)
+
+const (
+	SecurityNone     = "none"     // Plain text connection
+	SecurityStartTLS = "starttls" // Upgrade with STARTTLS after EHLO
+	SecurityTLS      = "tls"      // Implicit TLS (SMTPS)
+)
+
+// Config SMTP transport settings
+type Config struct {
+	Host        string
+	Port        int
+	Username    string
+	Password    string
+	Security    string // none, starttls or tls
+	Auth        string // plain, login or empty to pick from the server's EHLO reply
+	HeloName    string
+	PoolSize    int           // Maximum number of idle connections kept open
+	IdleTimeout time.Duration // Idle connections older than this are closed instead of reused
+	Timeout     time.Duration // Dial and per-transaction timeout
+	TLSConfig   *tls.Config
+}
+
+// Transport delivers messages through an SMTP server (implements mail.Transport)
+type Transport struct {
+	cfg  Config
+	idle chan *conn
+}
+
+// New creates an SMTP transport
+func New(cfg Config) *Transport {
+	if cfg.Security == "" {
+		cfg.Security = SecurityStartTLS
+	}
+	if cfg.Port == 0 {
+		cfg.Port = defaultPort(cfg.Security)
+	}
+	if cfg.HeloName == "" {
+		cfg.HeloName = "localhost"
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 4
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{ServerName: cfg.Host}
+	}
+	return &Transport{
+		cfg:  cfg,
+		idle: make(chan *conn, cfg.PoolSize),
+	}
+}
+
+// NewTransport creates an SMTP transport from environment variables
+func NewTransport() (*Transport, error) {
+	security := config.GetEnv("SMTP_SECURITY", SecurityStartTLS)
+	switch security {
+	case SecurityNone, SecurityStartTLS, SecurityTLS:
+	default:
+		return nil, fmt.Errorf("unknown SMTP security mode: %s", security)
+	}
+	port, err := strconv.Atoi(config.GetEnv("SMTP_PORT", strconv.Itoa(defaultPort(security))))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+	poolSize, err := strconv.Atoi(config.GetEnv("SMTP_POOL_SIZE", "4"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_POOL_SIZE: %w", err)
+	}
+	return New(Config{
+		Host:     config.GetEnv("SMTP_HOST", "localhost"),
+		Port:     port,
+		Username: config.GetEnv("SMTP_USERNAME"),
+		Password: config.GetEnv("SMTP_PASSWORD"),
+		Security: security,
+		Auth:     config.GetEnv("SMTP_AUTH"),
+		HeloName: config.GetEnv("SMTP_HELO", "localhost"),
+		PoolSize: poolSize,
+	}), nil
+}
+
+func defaultPort(security string) int {
+	switch security {
+	case SecurityTLS:
+		return 465
+	case SecurityStartTLS:
+		return 587
+	default:
+		return 25
+	}
+}
+
+// Send delivers the message and returns its Message-ID
+func (t *Transport) Send(ctx context.Context, msg *mail.Message) (string, error) {
+	from, err := netmail.ParseAddress(msg.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid sender address: %w", err)
+	}
+	rcpts := make([]string, 0, len(msg.Recipients()))
+	for _, r := range msg.Recipients() {
+		addr, err := netmail.ParseAddress(r)
+		if err != nil {
+			return "", fmt.Errorf("invalid recipient address: %w", err)
+		}
+		rcpts = append(rcpts, addr.Address)
+	}
+
+	m := *msg
+	if m.MessageId == "" {
+		m.MessageId = mail.NewMessageId(m.From)
+	}
+	data, err := m.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	c, err := t.get(ctx)
+	if err != nil {
+		// Connection problems are transient
+		return "", &mail.Error{Err: err, Retryable: true}
+	}
+	c.setDeadline(ctx, t.cfg.Timeout)
+	if err := c.send(from.Address, rcpts, data); err != nil {
+		// Server rejections leave the session usable; anything else is a broken connection
+		var smtpErr *Error
+		if !errors.As(err, &smtpErr) {
+			c.close()
+			return "", &mail.Error{Err: err, Retryable: true}
+		}
+		if c.reset() == nil {
+			t.put(c)
+		} else {
+			c.close()
+		}
+		return "", err
+	}
+	t.put(c)
+	return m.MessageId, nil
+}
+
+// Close closes all idle connections
+func (t *Transport) Close() error {
+	for {
+		select {
+		case c := <-t.idle:
+			c.quit()
+		default:
+			return nil
+		}
+	}
+}
+
+// get returns a healthy pooled connection or dials a new one
+func (t *Transport) get(ctx context.Context) (*conn, error) {
+	for {
+		select {
+		case c := <-t.idle:
+			if time.Since(c.lastUsed) > t.cfg.IdleTimeout {
+				c.quit()
+				continue
+			}
+			c.setDeadline(ctx, t.cfg.Timeout)
+			if err := c.reset(); err != nil {
+				c.close()
+				continue
+			}
+			return c, nil
+		default:
+			return dial(ctx, t.cfg)
+		}
+	}
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (t *Transport) put(c *conn) {
+	c.lastUsed = time.Now()
+	select {
+	case t.idle <- c:
+	default:
+		c.quit()
+	}
+}
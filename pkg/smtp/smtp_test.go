@@ -0,0 +1,204 @@
+package smtp_test
+
+import (
+	"aws-ses-sender-go/pkg/mail"
+	"aws-ses-sender-go/pkg/smtp"
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is a minimal SMTP server recording the commands it receives
+type fakeServer struct {
+	listener   net.Listener
+	rejectRcpt string
+	mu         sync.Mutex
+	commands   []string
+	messages   []string
+	sessions   int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: l}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *fakeServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeServer) serve() {
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.sessions++
+		s.mu.Unlock()
+		go s.handle(nc)
+	}
+}
+
+func (s *fakeServer) handle(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	reply := func(line string) { fmt.Fprintf(nc, "%s\r\n", line) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		switch cmd := strings.ToUpper(line); {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake")
+			reply("250-PIPELINING")
+			reply("250 AUTH PLAIN LOGIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "AUTH LOGIN"):
+			reply("334 VXNlcm5hbWU6")
+			_, _ = r.ReadString('\n')
+			reply("334 UGFzc3dvcmQ6")
+			_, _ = r.ReadString('\n')
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			if s.rejectRcpt != "" && strings.Contains(line, s.rejectRcpt) {
+				reply("550 5.1.1 User unknown")
+			} else {
+				reply("250 2.1.5 Ok")
+			}
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 2.0.0 Ok: queued")
+		case cmd == "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("250 Ok")
+		}
+	}
+}
+
+func testMessage() *mail.Message {
+	return &mail.Message{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Hello",
+		Html:    "<p>Hello</p>",
+	}
+}
+
+// TestSend_PipelinedWithPlainAuth tests a pipelined transaction with AUTH PLAIN
+func TestSend_PipelinedWithPlainAuth(t *testing.T) {
+	server := newFakeServer(t)
+	transport := smtp.New(smtp.Config{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Security: smtp.SecurityNone,
+		Username: "user",
+		Password: "pass",
+	})
+
+	messageId, err := transport.Send(context.TODO(), testMessage())
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, messageId)
+	require.Len(t, server.messages, 1)
+	assert.Contains(t, server.messages[0], "Subject: Hello")
+	assert.Contains(t, server.commands, "AUTH PLAIN AHVzZXIAcGFzcw==")
+	assert.Contains(t, server.commands, "RCPT TO:<user@example.com>")
+}
+
+// TestSend_LoginAuth tests the AUTH LOGIN exchange
+func TestSend_LoginAuth(t *testing.T) {
+	server := newFakeServer(t)
+	transport := smtp.New(smtp.Config{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Security: smtp.SecurityNone,
+		Auth:     "login",
+		Username: "user",
+		Password: "pass",
+	})
+
+	_, err := transport.Send(context.TODO(), testMessage())
+
+	require.NoError(t, err)
+	assert.Contains(t, server.commands, "AUTH LOGIN")
+	assert.Len(t, server.messages, 1)
+}
+
+// TestSend_RecipientRejected tests that a refused recipient surfaces the server reply
+func TestSend_RecipientRejected(t *testing.T) {
+	server := newFakeServer(t)
+	server.rejectRcpt = "unknown@example.com"
+	transport := smtp.New(smtp.Config{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Security: smtp.SecurityNone,
+	})
+	msg := testMessage()
+	msg.To = []string{"unknown@example.com"}
+
+	_, err := transport.Send(context.TODO(), msg)
+
+	var smtpErr *smtp.Error
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, 550, smtpErr.Code)
+	assert.Equal(t, "550 5.1.1 User unknown", err.Error())
+	assert.Empty(t, server.messages)
+}
+
+// TestSend_ReusesPooledConnection tests that consecutive sends share one session
+func TestSend_ReusesPooledConnection(t *testing.T) {
+	server := newFakeServer(t)
+	transport := smtp.New(smtp.Config{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Security: smtp.SecurityNone,
+	})
+	defer transport.Close()
+
+	for i := 0; i < 3; i++ {
+		msg := testMessage()
+		msg.Subject = "Hello " + strconv.Itoa(i)
+		_, err := transport.Send(context.TODO(), msg)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, server.sessions)
+	assert.Len(t, server.messages, 3)
+}
@@ -0,0 +1,231 @@
+package sns
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("sns: unsupported signature version")
+	ErrUntrustedCert      = errors.New("sns: signing certificate URL is not trusted")
+	ErrInvalidSignature   = errors.New("sns: invalid signature")
+	ErrTopicNotAllowed    = errors.New("sns: topic is not allowed")
+	ErrStaleMessage       = errors.New("sns: message timestamp is outside the accepted window")
+)
+
+// DefaultMaxAge is how old a message may be by default; SNS retries a failed delivery for at most about an hour
+const DefaultMaxAge = time.Hour
+
+// clockSkew is how far in the future a message timestamp may be
+const clockSkew = 5 * time.Minute
+
+// DefaultCertHost matches the hosts SNS serves signing certificates from. The region must be a single
+// region-shaped label (e.g. ap-northeast-2): a looser pattern also matches sns.s3.amazonaws.com,
+// the virtual-hosted S3 bucket "sns", which anyone could own.
+var DefaultCertHost = regexp.MustCompile(`^sns\.[a-z]{2}(-[a-z]+)+-[0-9]+\.amazonaws\.com(\.cn)?$`)
+
+// Message is a message POSTed by SNS to an HTTP(S) subscription
+type Message struct {
+	Type             string `json:"Type"`
+	MessageId        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	UnsubscribeURL   string `json:"UnsubscribeURL"`
+}
+
+// stringToSign builds the canonical form SNS signs for the message type
+func (m *Message) stringToSign() string {
+	var fields []string
+	switch m.Type {
+	case "SubscriptionConfirmation", "UnsubscribeConfirmation":
+		fields = []string{
+			"Message", m.Message,
+			"MessageId", m.MessageId,
+			"SubscribeURL", m.SubscribeURL,
+			"Timestamp", m.Timestamp,
+			"Token", m.Token,
+			"TopicArn", m.TopicArn,
+			"Type", m.Type,
+		}
+	default:
+		fields = []string{"Message", m.Message, "MessageId", m.MessageId}
+		// Subject is only signed when the notification has one
+		if m.Subject != "" {
+			fields = append(fields, "Subject", m.Subject)
+		}
+		fields = append(fields, "Timestamp", m.Timestamp, "TopicArn", m.TopicArn, "Type", m.Type)
+	}
+	return strings.Join(fields, "\n") + "\n"
+}
+
+// Verifier checks that messages were signed by SNS and come from an allowed topic
+type Verifier struct {
+	Client    *http.Client  // Fetches signing certificates (default: a client with a 10s timeout)
+	CertHosts []string      // Exact hosts certificates may be fetched from (default: hosts matching DefaultCertHost)
+	TopicArns []string      // Allowed topics; empty allows none, because any AWS account can sign for its own topic
+	MaxAge    time.Duration // Older messages are rejected as replays (default DefaultMaxAge)
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+// Verify returns nil when the message signature is valid, its topic is allowed and it is recent
+func (v *Verifier) Verify(ctx context.Context, m *Message) error {
+	if !slices.Contains(v.TopicArns, m.TopicArn) {
+		return fmt.Errorf("%w: %s", ErrTopicNotAllowed, m.TopicArn)
+	}
+	if err := v.checkTimestamp(m.Timestamp); err != nil {
+		return err
+	}
+
+	var hash crypto.Hash
+	var digest []byte
+	switch m.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(m.stringToSign()))
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256([]byte(m.stringToSign()))
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedVersion, m.SignatureVersion)
+	}
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	cert, err := v.certificate(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: certificate has no RSA key", ErrInvalidSignature)
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// checkTimestamp rejects messages signed too long ago (or ahead of the clock) to limit replays
+func (v *Verifier) checkTimestamp(timestamp string) error {
+	at, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrStaleMessage, timestamp)
+	}
+	maxAge := v.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if age := time.Since(at); age > maxAge || age < -clockSkew {
+		return fmt.Errorf("%w: %s", ErrStaleMessage, timestamp)
+	}
+	return nil
+}
+
+// certificate returns the signing certificate, fetching it once per URL
+func (v *Verifier) certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if err := v.checkCertURL(certURL); err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	cert, ok := v.certs[certURL]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+
+	cert, err := v.fetch(ctx, certURL)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	if v.certs == nil {
+		v.certs = make(map[string]*x509.Certificate)
+	}
+	v.certs[certURL] = cert
+	v.mu.Unlock()
+	return cert, nil
+}
+
+// checkCertURL only allows HTTPS PEM files on the allowed hosts
+func (v *Verifier) checkCertURL(certURL string) error {
+	u, err := url.Parse(certURL)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Path, ".pem") {
+		return fmt.Errorf("%w: %s", ErrUntrustedCert, certURL)
+	}
+	if !allowedHost(u, v.CertHosts) {
+		return fmt.Errorf("%w: %s", ErrUntrustedCert, certURL)
+	}
+	return nil
+}
+
+// allowedHost reports whether the URL's host is one of hosts (default: a host matching DefaultCertHost)
+func allowedHost(u *url.URL, hosts []string) bool {
+	host := strings.ToLower(u.Hostname())
+	if len(hosts) == 0 {
+		return DefaultCertHost.MatchString(host)
+	}
+	return slices.ContainsFunc(hosts, func(h string) bool { return strings.EqualFold(h, host) })
+}
+
+func (v *Verifier) fetch(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sns: fetch signing certificate: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sns: fetch signing certificate: %s", res.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("sns: fetch signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM certificate", ErrUntrustedCert)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUntrustedCert, err)
+	}
+	if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, fmt.Errorf("%w: certificate is expired or not yet valid", ErrUntrustedCert)
+	}
+	return cert, nil
+}
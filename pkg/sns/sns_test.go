@@ -0,0 +1,295 @@
+package sns_test
+
+import (
+	"aws-ses-sender-go/pkg/sns"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const topicArn = "arn:aws:sns:ap-northeast-2:123456789012:ses-results"
+
+// signer serves a locally generated signing certificate over HTTPS and signs messages with its key
+type signer struct {
+	key     *rsa.PrivateKey
+	server  *httptest.Server
+	fetches atomic.Int32
+}
+
+func newSigner(t *testing.T) *signer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.ap-northeast-2.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	s := &signer{key: key}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		_, _ = w.Write(certPEM)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// verifier trusts the test server's host
+func (s *signer) verifier() *sns.Verifier {
+	return &sns.Verifier{
+		Client:    s.server.Client(),
+		CertHosts: []string{"127.0.0.1"},
+		TopicArns: []string{topicArn},
+	}
+}
+
+// sign fills in the signature fields the way SNS does
+func (s *signer) sign(t *testing.T, m *sns.Message, version string) {
+	m.SignatureVersion = version
+	m.SigningCertURL = s.server.URL + "/SimpleNotificationService-test.pem"
+
+	canonical := "Message\n" + m.Message + "\nMessageId\n" + m.MessageId + "\n"
+	if m.Type == "Notification" {
+		if m.Subject != "" {
+			canonical += "Subject\n" + m.Subject + "\n"
+		}
+		canonical += "Timestamp\n" + m.Timestamp + "\nTopicArn\n" + m.TopicArn + "\nType\n" + m.Type + "\n"
+	} else {
+		canonical += "SubscribeURL\n" + m.SubscribeURL + "\nTimestamp\n" + m.Timestamp + "\nToken\n" + m.Token +
+			"\nTopicArn\n" + m.TopicArn + "\nType\n" + m.Type + "\n"
+	}
+
+	var signature []byte
+	var err error
+	if version == "1" {
+		sum := sha1.Sum([]byte(canonical))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA1, sum[:])
+	} else {
+		sum := sha256.Sum256([]byte(canonical))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	}
+	require.NoError(t, err)
+	m.Signature = base64.StdEncoding.EncodeToString(signature)
+}
+
+// now formats the current time the way SNS timestamps messages
+func now() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func notification() *sns.Message {
+	return &sns.Message{
+		Type:      "Notification",
+		MessageId: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:  topicArn,
+		Message:   `{"eventType":"Delivery","mail":{"messageId":"0100018c"}}`,
+		Timestamp: now(),
+	}
+}
+
+// TestVerify_SignatureVersions tests that SHA1 (version 1) and SHA256 (version 2) signatures are accepted
+func TestVerify_SignatureVersions(t *testing.T) {
+	s := newSigner(t)
+	v := s.verifier()
+
+	for _, version := range []string{"1", "2"} {
+		m := notification()
+		s.sign(t, m, version)
+		assert.NoError(t, v.Verify(context.Background(), m), "version %s", version)
+	}
+
+	confirmation := &sns.Message{
+		Type:         "SubscriptionConfirmation",
+		MessageId:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:        "2336412f37",
+		TopicArn:     topicArn,
+		Message:      "You have chosen to subscribe to the topic",
+		SubscribeURL: "https://sns.ap-northeast-2.amazonaws.com/?Action=ConfirmSubscription",
+		Timestamp:    now(),
+	}
+	s.sign(t, confirmation, "2")
+	assert.NoError(t, v.Verify(context.Background(), confirmation))
+}
+
+// TestVerify_Tampered tests that a modified message is rejected
+func TestVerify_Tampered(t *testing.T) {
+	s := newSigner(t)
+	m := notification()
+	s.sign(t, m, "2")
+	m.Message = strings.Replace(m.Message, "Delivery", "Bounce", 1)
+
+	assert.ErrorIs(t, s.verifier().Verify(context.Background(), m), sns.ErrInvalidSignature)
+}
+
+// TestVerify_UntrustedCert tests that certificates outside the allowed hosts are never fetched
+func TestVerify_UntrustedCert(t *testing.T) {
+	s := newSigner(t)
+	m := notification()
+	s.sign(t, m, "2")
+
+	v := s.verifier()
+	v.CertHosts = nil // Only the SNS hosts
+	assert.ErrorIs(t, v.Verify(context.Background(), m), sns.ErrUntrustedCert)
+
+	m.SigningCertURL = strings.Replace(m.SigningCertURL, "https://", "http://", 1)
+	assert.ErrorIs(t, s.verifier().Verify(context.Background(), m), sns.ErrUntrustedCert)
+	assert.Zero(t, s.fetches.Load())
+}
+
+// TestDefaultCertHost tests that only single-label regions of the SNS hosts are trusted by default
+func TestDefaultCertHost(t *testing.T) {
+	for _, host := range []string{"sns.ap-northeast-2.amazonaws.com", "sns.cn-north-1.amazonaws.com.cn", "sns.us-gov-west-1.amazonaws.com"} {
+		assert.True(t, sns.DefaultCertHost.MatchString(host), host)
+	}
+	for _, host := range []string{
+		"sns.s3.amazonaws.com", // Virtual-hosted S3 bucket "sns"
+		"sns.evil.s3.amazonaws.com",
+		"sns.ap-northeast-2.amazonaws.com.evil.com",
+		"evilsns.ap-northeast-2.amazonaws.com",
+		"sns..amazonaws.com",
+	} {
+		assert.False(t, sns.DefaultCertHost.MatchString(host), host)
+	}
+}
+
+// TestVerify_S3BucketHost tests that a certificate or subscribe URL on the S3 bucket "sns" is never visited
+func TestVerify_S3BucketHost(t *testing.T) {
+	s := newSigner(t)
+	m := notification()
+	s.sign(t, m, "2")
+	m.SigningCertURL = "https://sns.s3.amazonaws.com/SimpleNotificationService-test.pem"
+
+	v := s.verifier()
+	v.CertHosts = nil // Only the SNS hosts
+	assert.ErrorIs(t, v.Verify(context.Background(), m), sns.ErrUntrustedCert)
+
+	confirmation := &sns.Message{
+		Type:         "SubscriptionConfirmation",
+		TopicArn:     topicArn,
+		SubscribeURL: "https://sns.s3.amazonaws.com/?Action=ConfirmSubscription",
+	}
+	_, err := sns.Confirm(context.Background(), s.server.Client(), nil, confirmation)
+	assert.ErrorIs(t, err, sns.ErrUntrustedSubscribeURL)
+	assert.Zero(t, s.fetches.Load())
+}
+
+// TestVerify_TopicNotAllowed tests the TopicArn allow-list
+func TestVerify_TopicNotAllowed(t *testing.T) {
+	s := newSigner(t)
+	m := notification()
+	m.TopicArn = "arn:aws:sns:ap-northeast-2:999999999999:other"
+	s.sign(t, m, "2")
+
+	assert.ErrorIs(t, s.verifier().Verify(context.Background(), m), sns.ErrTopicNotAllowed)
+}
+
+// TestVerify_NoAllowList tests that without allowed topics every message is rejected
+func TestVerify_NoAllowList(t *testing.T) {
+	s := newSigner(t)
+	m := notification()
+	s.sign(t, m, "2")
+	v := s.verifier()
+	v.TopicArns = nil
+
+	assert.ErrorIs(t, v.Verify(context.Background(), m), sns.ErrTopicNotAllowed)
+	assert.Zero(t, s.fetches.Load())
+}
+
+// TestVerify_Stale tests that validly signed messages outside the accepted time window are rejected as replays
+func TestVerify_Stale(t *testing.T) {
+	s := newSigner(t)
+	v := s.verifier()
+	v.MaxAge = 10 * time.Minute
+
+	for _, timestamp := range []string{
+		time.Now().Add(-11 * time.Minute).UTC().Format(time.RFC3339),
+		time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		"2024-01-02T09:00:00.000Z",
+		"yesterday",
+	} {
+		m := notification()
+		m.Timestamp = timestamp
+		s.sign(t, m, "2")
+		assert.ErrorIs(t, v.Verify(context.Background(), m), sns.ErrStaleMessage, timestamp)
+	}
+
+	m := notification()
+	m.Timestamp = time.Now().Add(-9 * time.Minute).UTC().Format(time.RFC3339)
+	s.sign(t, m, "2")
+	assert.NoError(t, v.Verify(context.Background(), m))
+}
+
+// TestVerify_UnsupportedVersion tests that unknown signature versions are rejected
+func TestVerify_UnsupportedVersion(t *testing.T) {
+	s := newSigner(t)
+	m := notification()
+	s.sign(t, m, "2")
+	m.SignatureVersion = "3"
+
+	assert.ErrorIs(t, s.verifier().Verify(context.Background(), m), sns.ErrUnsupportedVersion)
+}
+
+// TestVerify_CachesCert tests that the signing certificate is fetched once
+func TestVerify_CachesCert(t *testing.T) {
+	s := newSigner(t)
+	v := s.verifier()
+
+	for range 3 {
+		m := notification()
+		s.sign(t, m, "2")
+		require.NoError(t, v.Verify(context.Background(), m))
+	}
+	assert.Equal(t, int32(1), s.fetches.Load())
+}
+
+// TestConfirm tests that the SubscribeURL is visited and the subscription ARN returned
+func TestConfirm(t *testing.T) {
+	var visited atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visited.Add(1)
+		assert.Equal(t, "ConfirmSubscription", r.URL.Query().Get("Action"))
+		_, _ = w.Write([]byte(`<ConfirmSubscriptionResponse xmlns="http://sns.amazonaws.com/doc/2010-03-31/">
+  <ConfirmSubscriptionResult>
+    <SubscriptionArn>` + topicArn + `:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55</SubscriptionArn>
+  </ConfirmSubscriptionResult>
+</ConfirmSubscriptionResponse>`))
+	}))
+	defer server.Close()
+	m := &sns.Message{
+		Type:         "SubscriptionConfirmation",
+		TopicArn:     topicArn,
+		SubscribeURL: server.URL + "/?Action=ConfirmSubscription&TopicArn=" + topicArn + "&Token=2336412f37",
+	}
+
+	arn, err := sns.Confirm(context.Background(), server.Client(), []string{"127.0.0.1"}, m)
+
+	require.NoError(t, err)
+	assert.Equal(t, topicArn+":2bcfbf39-05c3-41de-beaa-fcfcc21c8f55", arn)
+	assert.Equal(t, int32(1), visited.Load())
+
+	// Only SNS hosts are visited by default
+	_, err = sns.Confirm(context.Background(), server.Client(), nil, m)
+	assert.ErrorIs(t, err, sns.ErrUntrustedSubscribeURL)
+	assert.Equal(t, int32(1), visited.Load())
+}
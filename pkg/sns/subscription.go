@@ -0,0 +1,51 @@
+package sns
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrUntrustedSubscribeURL = errors.New("sns: subscribe URL is not trusted")
+
+// Confirm confirms the subscription of a verified SubscriptionConfirmation message by visiting its
+// SubscribeURL, and returns the subscription ARN. The URL must be HTTPS on one of hosts
+// (default: hosts matching DefaultCertHost) so that a message cannot make the service call arbitrary URLs.
+func Confirm(ctx context.Context, client *http.Client, hosts []string, m *Message) (string, error) {
+	u, err := url.Parse(m.SubscribeURL)
+	if err != nil || u.Scheme != "https" || !allowedHost(u, hosts) {
+		return "", fmt.Errorf("%w: %s", ErrUntrustedSubscribeURL, m.SubscribeURL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sns: confirm subscription: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("sns: confirm subscription: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("sns: confirm subscription: %s: %s", res.Status, body)
+	}
+
+	var out struct {
+		SubscriptionArn string `xml:"ConfirmSubscriptionResult>SubscriptionArn"`
+	}
+	if err := xml.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("sns: confirm subscription: %w", err)
+	}
+	return out.SubscriptionArn, nil
+}